@@ -0,0 +1,176 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "assert",
+		usage:    "check that all assertions hold, exit code 1 if any does not (endpoints.ready == endpoints.total ...)",
+		run:      runAssert,
+		readOnly: true,
+	})
+}
+
+// assertValues are the values assertions can refer to.
+var assertValues = map[string]func(s statusSummary) int{
+	"endpoints.total":     func(s statusSummary) int { return s.endpoints },
+	"endpoints.ready":     func(s statusSummary) int { return s.readyEndpoints },
+	"controllers.failing": func(s statusSummary) int { return s.failingControllers },
+	"clusters.total":      func(s statusSummary) int { return s.clusters },
+	"clusters.ready":      func(s statusSummary) int { return s.readyClusters },
+}
+
+var assertOperators = map[string]func(a, b int) bool{
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+}
+
+func assertValueNames() []string {
+	names := make([]string, 0, len(assertValues))
+	for name := range assertValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// assertion compares two operands, each either one of assertValues or an
+// integer.
+type assertion struct {
+	left, op, right string
+}
+
+func (a assertion) String() string {
+	return a.left + " " + a.op + " " + a.right
+}
+
+// eval returns whether a holds for s, along with the values of its operands.
+func (a assertion) eval(s statusSummary) (ok bool, left, right int) {
+	left, right = assertOperand(a.left, s), assertOperand(a.right, s)
+	return assertOperators[a.op](left, right), left, right
+}
+
+func assertOperand(operand string, s statusSummary) int {
+	if value, ok := assertValues[operand]; ok {
+		return value(s)
+	}
+	n, _ := strconv.Atoi(operand)
+	return n
+}
+
+// tokenizeAssertions splits str into operands and operators. Operators need
+// not be surrounded by spaces, and commas may separate assertions.
+func tokenizeAssertions(str string) []string {
+	var tokens []string
+	for i := 0; i < len(str); {
+		switch ch := str[i]; {
+		case ch == ' ' || ch == '\t' || ch == ',':
+			i++
+		case strings.ContainsRune("=!<>", rune(ch)):
+			j := i + 1
+			if j < len(str) && str[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, str[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(str) && !strings.ContainsRune(" \t,=!<>", rune(str[j])) {
+				j++
+			}
+			tokens = append(tokens, str[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// parseAssertions parses a list of assertions of the form
+// "OPERAND OPERATOR OPERAND", e.g. "endpoints.ready == endpoints.total".
+func parseAssertions(str string) ([]assertion, error) {
+	tokens := tokenizeAssertions(str)
+	if len(tokens)%3 != 0 {
+		return nil, fmt.Errorf("invalid assertions %q, must be of the form OPERAND OPERATOR OPERAND", str)
+	}
+	var assertions []assertion
+	for i := 0; i < len(tokens); i += 3 {
+		a := assertion{tokens[i], tokens[i+1], tokens[i+2]}
+		if _, ok := assertOperators[a.op]; !ok {
+			return nil, fmt.Errorf("invalid assertion %q: unknown operator %q", a, a.op)
+		}
+		for _, operand := range []string{a.left, a.right} {
+			if _, ok := assertValues[operand]; ok {
+				continue
+			}
+			if _, err := strconv.Atoi(operand); err != nil {
+				return nil, fmt.Errorf("invalid assertion %q: unknown value %q, must be an integer or one of: %s",
+					a, operand, strings.Join(assertValueNames(), ", "))
+			}
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+func runAssert(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("assert", flag.ExitOnError)
+	fs.Parse(args)
+
+	assertions, err := parseAssertions(strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+	if len(assertions) == 0 {
+		return errors.New("missing assertion, e.g. endpoints.ready == endpoints.total")
+	}
+
+	s, err := pollStatusSummary(ctx, c)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, a := range assertions {
+		ok, left, right := a.eval(s)
+		result := colorize(colorGreen, "true")
+		if !ok {
+			result = colorize(colorRed, "false")
+			failed = true
+		}
+		fmt.Fprintf(out, "%s: %s (%d %s %d)\n", a, result, left, a.op, right)
+	}
+	if failed {
+		return errUnhealthy
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAssertions(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    []assertion
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"endpoints.ready == endpoints.total", []assertion{{"endpoints.ready", "==", "endpoints.total"}}, ""},
+		{"endpoints.ready>=3,controllers.failing==0", []assertion{
+			{"endpoints.ready", ">=", "3"},
+			{"controllers.failing", "==", "0"},
+		}, ""},
+		{"clusters.ready != -1 clusters.total<2", []assertion{
+			{"clusters.ready", "!=", "-1"},
+			{"clusters.total", "<", "2"},
+		}, ""},
+		{"endpoints.ready ==", nil, "must be of the form"},
+		{"endpoints.ready = 3", nil, `unknown operator "="`},
+		{"endpoints.ready == pods.total", nil, `unknown value "pods.total"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			got, err := parseAssertions(tt.str)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseAssertions() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAssertions() = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAssertions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertionEval(t *testing.T) {
+	s := statusSummary{endpoints: 5, readyEndpoints: 4, failingControllers: 0, clusters: 2, readyClusters: 2}
+	tests := []struct {
+		a           assertion
+		ok          bool
+		left, right int
+	}{
+		{assertion{"endpoints.ready", "==", "endpoints.total"}, false, 4, 5},
+		{assertion{"clusters.ready", "==", "clusters.total"}, true, 2, 2},
+		{assertion{"controllers.failing", "<=", "0"}, true, 0, 0},
+		{assertion{"endpoints.total", ">", "5"}, false, 5, 5},
+		{assertion{"3", "<", "endpoints.ready"}, true, 3, 4},
+	}
+	for _, tt := range tests {
+		ok, left, right := tt.a.eval(s)
+		if ok != tt.ok || left != tt.left || right != tt.right {
+			t.Errorf("%s = %t (%d, %d), want %t (%d, %d)", tt.a, ok, left, right, tt.ok, tt.left, tt.right)
+		}
+	}
+}
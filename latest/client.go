@@ -0,0 +1,256 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	clientapi "github.com/cilium/cilium/api/v1/client"
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/pkg/client"
+
+	runtimeclient "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+)
+
+// clientOptions configure the API clients created by newClient.
+type clientOptions struct {
+	// host is the address of the agent, the default socket
+	// /var/run/cilium/cilium.sock if empty. Plain paths are taken as UNIX
+	// sockets.
+	host string
+
+	// timeout bounds each attempt to reach the agent while waiting for it.
+	timeout time.Duration
+
+	// waitForAgent is how long to retry reaching the agent before giving
+	// up, 0 to not check whether it is reachable at all.
+	waitForAgent time.Duration
+
+	// debugHTTP dumps every HTTP request and response to stderr, along
+	// with their bodies if debugHTTPBody is set too.
+	debugHTTP     bool
+	debugHTTPBody bool
+
+	// tls, if set, is used to reach a tcp:// host over HTTPS, e.g. an agent
+	// API exposed through a TLS terminating proxy. UNIX sockets do not
+	// support it.
+	tls *tls.Config
+}
+
+// clientOptionsFromFlags returns the options given on the command line for
+// the agent listening on host.
+func clientOptionsFromFlags(host string) (clientOptions, error) {
+	tlsConfig, err := loadTLSConfig(*tlsCA, *tlsCert, *tlsKey)
+	if err != nil {
+		return clientOptions{}, err
+	}
+	return clientOptions{
+		host:          host,
+		timeout:       *timeout,
+		waitForAgent:  *waitForAgent,
+		debugHTTP:     *debugHTTP || *debugHTTPBody,
+		debugHTTPBody: *debugHTTPBody,
+		tls:           tlsConfig,
+	}, nil
+}
+
+// loadTLSConfig returns the TLS configuration verifying the agent with the
+// CA certificate in caFile, or the system roots if empty, and authenticating
+// the client with the certificate and key in certFile and keyFile, if set.
+// It returns nil if none of the files is given, as TLS is not used then.
+func loadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("-tls-cert and -tls-key must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// newClient returns a client connected to the agent described by opts. All
+// API calls made through it are logged at debug level. As with
+// client.NewClient, the host may be a unix:// or a tcp:// URL.
+func newClient(opts clientOptions) (*client.Client, error) {
+	host := opts.host
+	switch {
+	case host == "":
+		host = client.DefaultSockPath()
+	case !strings.Contains(host, "://"):
+		host = "unix://" + host
+	}
+	parts := strings.SplitN(host, "://", 2)
+	proto, addr := parts[0], parts[1]
+
+	tr := &http.Transport{}
+	schemes := clientapi.DefaultSchemes
+	switch proto {
+	case "unix":
+		if opts.tls != nil {
+			return nil, fmt.Errorf("invalid host %q, TLS is only supported with tcp:// URLs", opts.host)
+		}
+		// No need for compression in local communications.
+		tr.DisableCompression = true
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}
+	case "tcp":
+		tr.Proxy = http.ProxyFromEnvironment
+		tr.DialContext = (&net.Dialer{}).DialContext
+		if opts.tls != nil {
+			tr.TLSClientConfig = opts.tls
+			schemes = []string{"https"}
+		}
+	default:
+		return nil, fmt.Errorf("invalid host %q, must be a path or a unix:// or tcp:// URL", opts.host)
+	}
+	var rt http.RoundTripper = tr
+	if opts.debugHTTP {
+		rt = &debugRoundTripper{next: rt, w: os.Stderr, bodies: opts.debugHTTPBody}
+	}
+
+	transport := runtimeclient.NewWithClient(addr, clientapi.DefaultBasePath, schemes, &http.Client{Transport: rt})
+	c := &client.Client{CiliumAPI: *clientapi.New(transport, strfmt.Default)}
+	c.SetTransport(loggingTransport{c.Transport})
+
+	if opts.waitForAgent > 0 {
+		if err := connectWithRetry(c, opts); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// connectWithRetry polls the health of the agent until it answers, retrying
+// transient errors for up to opts.waitForAgent.
+func connectWithRetry(c *client.Client, opts clientOptions) error {
+	deadline := time.Now().Add(opts.waitForAgent)
+	interval := waitMinInterval
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		_, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+		cancel()
+		switch {
+		case err == nil:
+			return nil
+		case !isRetryable(err):
+			return err
+		case time.Now().Add(interval).After(deadline):
+			return fmt.Errorf("agent not reachable after %s: %w", opts.waitForAgent, err)
+		}
+		log.WithError(client.Hint(err)).Debug("Agent not reachable yet, retrying")
+
+		time.Sleep(interval)
+		if interval *= 2; interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+// debugRoundTripper dumps the request line and the response status and
+// duration of every HTTP call to w. Bodies are only dumped if bodies is set,
+// as they may hold sensitive data.
+type debugRoundTripper struct {
+	next   http.RoundTripper
+	w      io.Writer
+	bodies bool
+}
+
+// debugDumpMu keeps the dumps of concurrent calls apart. It is shared by all
+// clients, as each agent given with -sockets has a client of its own.
+var debugDumpMu sync.Mutex
+
+func (t *debugRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s %s\n", req.Method, req.URL.RequestURI())
+	if t.bodies && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		dumpBody(&buf, body)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&buf, "< error after %s: %s\n", time.Since(start), err)
+	} else {
+		fmt.Fprintf(&buf, "< %s after %s\n", resp.Status, time.Since(start))
+		if t.bodies {
+			body, rerr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			dumpBody(&buf, body)
+			if rerr != nil {
+				// A RoundTripper returns either a response or an error.
+				fmt.Fprintf(&buf, "< error reading body: %s\n", rerr)
+				resp, err = nil, rerr
+			} else {
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+			}
+		}
+	}
+
+	debugDumpMu.Lock()
+	buf.WriteTo(t.w)
+	debugDumpMu.Unlock()
+	return resp, err
+}
+
+func dumpBody(w io.Writer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	w.Write(body)
+	if body[len(body)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+}
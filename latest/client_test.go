@@ -0,0 +1,285 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+// roundTripperFunc is an http.RoundTripper calling itself.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// errReader fails every read.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestDebugRoundTripper(t *testing.T) {
+	next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.WriteString(`{"state":"Ok"}`)
+		return rec.Result(), nil
+	})
+	var dump bytes.Buffer
+	rt := &debugRoundTripper{next: next, w: &dump, bodies: true}
+
+	req := httptest.NewRequest(http.MethodPatch, "http://localhost/v1/endpoint/1", strings.NewReader(`{"state":"waiting-to-regenerate"}`))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"state":"Ok"}` {
+		t.Errorf("response body = %q after dumping it", body)
+	}
+	for _, want := range []string{
+		"> PATCH /v1/endpoint/1\n",
+		`{"state":"waiting-to-regenerate"}` + "\n",
+		"< 200 OK after ",
+		`{"state":"Ok"}` + "\n",
+	} {
+		if !strings.Contains(dump.String(), want) {
+			t.Errorf("dump does not contain %q:\n%s", want, dump.String())
+		}
+	}
+}
+
+func TestDebugRoundTripperBodyError(t *testing.T) {
+	next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(errReader{})}, nil
+	})
+	rt := &debugRoundTripper{next: next, w: io.Discard, bodies: true}
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://localhost/v1/healthz", nil))
+	if err == nil || resp != nil {
+		t.Errorf("RoundTrip() = %v, %v, want only an error", resp, err)
+	}
+}
+
+// exclusiveWriter fails the test if it is written to concurrently.
+type exclusiveWriter struct {
+	t       *testing.T
+	writing int32
+}
+
+func (w *exclusiveWriter) Write(b []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&w.writing, 0, 1) {
+		w.t.Error("concurrent dumps interleaved")
+	}
+	time.Sleep(time.Millisecond)
+	atomic.StoreInt32(&w.writing, 0)
+	return len(b), nil
+}
+
+func TestDebugRoundTripperClientsShareLock(t *testing.T) {
+	next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return httptest.NewRecorder().Result(), nil
+	})
+	w := &exclusiveWriter{t: t}
+	// One round tripper per agent, as with -sockets.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		rt := &debugRoundTripper{next: next, w: w}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://localhost/v1/healthz", nil))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewClientHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{"", false},
+		{"/var/run/cilium/cilium.sock", false},
+		{"unix:///var/run/cilium/cilium.sock", false},
+		{"tcp://127.0.0.1:9234", false},
+		{"http://127.0.0.1:9234", true},
+	}
+	for _, tt := range tests {
+		// Without -wait-for-agent, the agent is not contacted.
+		_, err := newClient(clientOptions{host: tt.host})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newClient(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewClientUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&models.StatusResponse{Cilium: &models.Status{State: models.StatusStateOk}})
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	// A plain path is taken as a UNIX socket.
+	c, err := newClient(clientOptions{host: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Payload.Cilium.State != models.StatusStateOk {
+		t.Errorf("GetHealthz() = %+v", resp.Payload)
+	}
+}
+
+func TestNewClientWaitForAgent(t *testing.T) {
+	captureLogs(t)
+	agent, _ := newFakeAgent(t)
+	agent.handle("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if agent.callCount("GET /healthz") < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(&models.StatusResponse{})
+	})
+
+	opts := clientOptions{host: agent.host, timeout: time.Second, waitForAgent: 5 * time.Second}
+	if _, err := newClient(opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := agent.callCount("GET /healthz"); n != 3 {
+		t.Errorf("agent was reached %d times, want 3", n)
+	}
+}
+
+func TestNewClientWaitForAgentGivesUp(t *testing.T) {
+	captureLogs(t)
+	agent, _ := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusServiceUnavailable, nil)
+
+	opts := clientOptions{host: agent.host, timeout: time.Second, waitForAgent: 3 * waitMinInterval / 2}
+	_, err := newClient(opts)
+	if err == nil || !strings.HasPrefix(err.Error(), "agent not reachable after 150ms: ") {
+		t.Errorf("newClient() error = %v, want the agent not to be reachable", err)
+	}
+	if n := agent.callCount("GET /healthz"); n != 2 {
+		t.Errorf("agent was reached %d times, want 2", n)
+	}
+}
+
+// writeCertificate writes cert PEM encoded to a file and returns its path.
+func writeCertificate(t *testing.T, cert *x509.Certificate) string {
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&models.StatusResponse{Cilium: &models.Status{State: models.StatusStateOk}})
+	}))
+	defer srv.Close()
+	host := "tcp://" + srv.Listener.Addr().String()
+
+	config, err := loadTLSConfig(writeCertificate(t, srv.Certificate()), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newClient(clientOptions{host: host, tls: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Payload.Cilium.State != models.StatusStateOk {
+		t.Errorf("GetHealthz() = %+v", resp.Payload)
+	}
+
+	// Without TLS, the server does not answer plain HTTP.
+	c, err = newClient(clientOptions{host: host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParams()); err == nil {
+		t.Error("GetHealthz() over plain HTTP succeeded")
+	}
+
+	// UNIX sockets do not support TLS.
+	if _, err := newClient(clientOptions{host: "/var/run/cilium/cilium.sock", tls: config}); err == nil {
+		t.Error("newClient() with TLS on a UNIX socket succeeded")
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.crt")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name                      string
+		caFile, certFile, keyFile string
+		wantNil, wantErr          bool
+	}{
+		{"no TLS", "", "", "", true, false},
+		{"missing CA", "/nonexistent/ca.crt", "", "", true, true},
+		{"CA without certificate", empty, "", "", true, true},
+		{"cert without key", "", "client.crt", "", true, true},
+		{"key without cert", "", "", "client.key", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTLSConfig(tt.caFile, tt.certFile, tt.keyFile)
+			if (config == nil) != tt.wantNil || (err != nil) != tt.wantErr {
+				t.Errorf("loadTLSConfig() = %v, %v, want nil %t, error %t", config, err, tt.wantNil, tt.wantErr)
+			}
+		})
+	}
+}
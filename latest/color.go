@@ -0,0 +1,72 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+	colorReset  = "\x1b[0m"
+)
+
+// colorEnabled is set by setupColor according to -color.
+var colorEnabled bool
+
+// setupColor enables colored output if mode is "always", or if it is "auto"
+// and the output goes to a terminal.
+func setupColor(mode string, outPath string) error {
+	switch mode {
+	case "always":
+		colorEnabled = true
+	case "never":
+		colorEnabled = false
+	case "auto":
+		colorEnabled = (outPath == "" || outPath == "-") && term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		return fmt.Errorf("invalid color mode %q, must be one of: always, never, auto", mode)
+	}
+	return nil
+}
+
+// colorize returns s wrapped in the escape sequences for color, or s as is if
+// colored output is disabled.
+func colorize(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// colorState colors a health or status state: green if it is healthy, red if
+// it failed, and yellow for anything in between.
+func colorState(state string) string {
+	switch strings.ToLower(state) {
+	case "ok", "ready":
+		return colorize(colorGreen, state)
+	case "failure", "not-ready", "unreachable":
+		return colorize(colorRed, state)
+	}
+	return colorize(colorYellow, state)
+}
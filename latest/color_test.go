@@ -0,0 +1,70 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import "testing"
+
+func TestSetupColor(t *testing.T) {
+	defer func(enabled bool) { colorEnabled = enabled }(colorEnabled)
+	tests := []struct {
+		mode    string
+		outPath string
+		want    bool
+		wantErr bool
+	}{
+		{"always", "", true, false},
+		{"never", "", false, false},
+		// The test output is not a terminal, and a file never is.
+		{"auto", "", false, false},
+		{"auto", "out.txt", false, false},
+		{"sometimes", "", false, true},
+	}
+	for _, tt := range tests {
+		colorEnabled = false
+		err := setupColor(tt.mode, tt.outPath)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("setupColor(%q, %q) error = %v, wantErr %v", tt.mode, tt.outPath, err, tt.wantErr)
+		}
+		if colorEnabled != tt.want {
+			t.Errorf("setupColor(%q, %q) enabled color = %v, want %v", tt.mode, tt.outPath, colorEnabled, tt.want)
+		}
+	}
+}
+
+func TestColorState(t *testing.T) {
+	defer func(enabled bool) { colorEnabled = enabled }(colorEnabled)
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"Ok", colorGreen + "Ok" + colorReset},
+		{"ready", colorGreen + "ready" + colorReset},
+		{"Failure", colorRed + "Failure" + colorReset},
+		{"not-ready", colorRed + "not-ready" + colorReset},
+		{"Warning", colorYellow + "Warning" + colorReset},
+	}
+	colorEnabled = true
+	for _, tt := range tests {
+		if got := colorState(tt.state); got != tt.want {
+			t.Errorf("colorState(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+	colorEnabled = false
+	if got := colorState("Ok"); got != "Ok" {
+		t.Errorf("colorState() with color disabled = %q, want %q", got, "Ok")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:  "completion",
+		usage: "print a shell completion script (bash or zsh)",
+		run:   runCompletion,
+	})
+}
+
+// completionTemplate is a bash completion script. Subcommand names and global
+// flags are listed statically. The flags of a subcommand are only known once
+// it parses its arguments, so they are taken from the output of
+// "<command> -h" when completing.
+var completionTemplate = template.Must(template.New("completion").Parse(`# {{.Shell}} completion for {{.Program}}
+{{- if eq .Shell "zsh"}}
+autoload -U +X bashcompinit && bashcompinit
+{{- end}}
+
+{{.Func}}() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	local cmd="" i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		{{.ValueFlags}})
+			((i++)) ;;
+		-*) ;;
+		*)
+			cmd=${COMP_WORDS[i]}
+			break ;;
+		esac
+	done
+
+	if [[ -z $cmd ]]; then
+		if [[ $cur == -* ]]; then
+			COMPREPLY=($(compgen -W "{{.Flags}}" -- "$cur"))
+		else
+			COMPREPLY=($(compgen -W "{{.Commands}}" -- "$cur"))
+		fi
+	elif [[ $cur == -* ]]; then
+		local flags=$("${COMP_WORDS[0]}" "$cmd" -h 2>&1 | sed -n 's/^  \(-[^ ]*\).*/\1/p')
+		COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+	fi
+}
+
+complete -F {{.Func}} {{.Program}}
+`))
+
+func runCompletion(_ context.Context, _ *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || fs.Arg(0) != "bash" && fs.Arg(0) != "zsh" {
+		return errors.New("missing or unknown shell, use completion bash or completion zsh")
+	}
+	return writeCompletion(out, fs.Arg(0), filepath.Base(os.Args[0]))
+}
+
+// writeCompletion writes the completion script for shell, completing the
+// commands of the given program.
+func writeCompletion(w io.Writer, shell, program string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var flags, valueFlags []string
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "-"+f.Name)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			valueFlags = append(valueFlags, "-"+f.Name)
+		}
+	})
+
+	return completionTemplate.Execute(w, struct {
+		Shell, Program, Func        string
+		Commands, Flags, ValueFlags string
+	}{
+		Shell:      shell,
+		Program:    program,
+		Func:       "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(program, "_"),
+		Commands:   strings.Join(names, " "),
+		Flags:      strings.Join(flags, " "),
+		ValueFlags: strings.Join(valueFlags, "|"),
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteCompletion(t *testing.T) {
+	tests := []struct {
+		shell, program string
+		wantFunc       string
+	}{
+		{"bash", "client-example", "_client_example"},
+		{"zsh", "client-example", "_client_example"},
+		{"bash", "cilium.client", "_cilium_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell+" "+tt.program, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCompletion(&buf, tt.shell, tt.program); err != nil {
+				t.Fatal(err)
+			}
+			script := buf.String()
+
+			if want := "complete -F " + tt.wantFunc + " " + tt.program + "\n"; !strings.HasSuffix(script, want) {
+				t.Errorf("script does not end with %q", want)
+			}
+			if got := strings.Contains(script, "bashcompinit"); got != (tt.shell == "zsh") {
+				t.Errorf("script loads bashcompinit = %v, want %v", got, tt.shell == "zsh")
+			}
+			for _, name := range []string{"endpoints", "completion", "status"} {
+				if !regexp.MustCompile(`compgen -W "[^"]*\b` + name + `\b`).MatchString(script) {
+					t.Errorf("script does not complete command %s", name)
+				}
+			}
+			// Flags taking a value skip it when looking for the command,
+			// boolean flags do not.
+			valueFlags := regexp.MustCompile(`\n\t\t(-[^\n]*)\)\n`).FindStringSubmatch(script)
+			if valueFlags == nil {
+				t.Fatal("script has no case for flags taking a value")
+			}
+			cases := strings.Split(valueFlags[1], "|")
+			if !contains(cases, "-timeout") || contains(cases, "-no-headers") {
+				t.Errorf("flags taking a value = %v, want -timeout and not -no-headers", cases)
+			}
+
+			if tt.shell != "bash" {
+				return
+			}
+			bash, err := exec.LookPath("bash")
+			if err != nil {
+				t.Skip("bash not found")
+			}
+			cmd := exec.Command(bash, "-n")
+			cmd.Stdin = &buf
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("bash -n: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
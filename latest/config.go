@@ -0,0 +1,61 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// configCache memoizes the agent configuration so that commands polling the
+// agent do not fetch it on every iteration.
+type configCache struct {
+	c   *client.Client
+	ttl time.Duration
+
+	mu      sync.Mutex
+	config  *models.DaemonConfiguration
+	fetched time.Time
+}
+
+// cachedConfig returns a cache of the agent configuration whose entries
+// expire after ttl.
+func cachedConfig(c *client.Client, ttl time.Duration) *configCache {
+	return &configCache{c: c, ttl: ttl}
+}
+
+// get returns the cached configuration, or fetches it from the agent if it
+// is older than the TTL. Failed fetches are not cached.
+func (cc *configCache) get(ctx context.Context) (*models.DaemonConfiguration, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.config != nil && time.Since(cc.fetched) < cc.ttl {
+		return cc.config, nil
+	}
+	resp, err := cc.c.Daemon.GetConfig(daemon.NewGetConfigParamsWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	cc.config, cc.fetched = resp.Payload, time.Now()
+	return cc.config, nil
+}
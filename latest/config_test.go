@@ -0,0 +1,70 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestConfigCache(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /config", http.StatusInternalServerError, nil)
+	ctx := context.Background()
+
+	cc := cachedConfig(c, time.Hour)
+	if _, err := cc.get(ctx); err == nil {
+		t.Fatal("get() succeeded with a failing agent")
+	}
+
+	// Failed fetches are not cached.
+	agent.respond("GET /config", http.StatusOK, &models.DaemonConfiguration{
+		Status: &models.DaemonConfigurationStatus{NodeMonitor: &models.MonitorStatus{Cpus: 4}},
+	})
+	for i := 0; i < 3; i++ {
+		config, err := cc.get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Status.NodeMonitor.Cpus != 4 {
+			t.Errorf("get() = %+v, want the agent configuration", config)
+		}
+	}
+	if n := agent.callCount("GET /config"); n != 2 {
+		t.Errorf("agent was asked %d times for its configuration, want 2", n)
+	}
+}
+
+func TestConfigCacheExpires(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /config", http.StatusOK, &models.DaemonConfiguration{})
+	ctx := context.Background()
+
+	cc := cachedConfig(c, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := cc.get(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := agent.callCount("GET /config"); n != 3 {
+		t.Errorf("agent was asked %d times for its configuration, want 3", n)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "config-diff",
+		usage:    "compare the agent configuration against a baseline saved as JSON (-baseline FILE)",
+		run:      runConfigDiff,
+		readOnly: true,
+	})
+}
+
+// configChange is a configuration key whose value differs between the
+// baseline and the agent.
+type configChange struct {
+	Baseline string `json:"baseline"`
+	Live     string `json:"live"`
+}
+
+// configDiff is the difference between two configurations, keyed by the
+// dotted path of each value, e.g. "status.immutable.Debug".
+type configDiff struct {
+	Context string                  `json:"context,omitempty"`
+	Added   map[string]string       `json:"added"`
+	Removed map[string]string       `json:"removed"`
+	Changed map[string]configChange `json:"changed"`
+}
+
+func runConfigDiff(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("config-diff", flag.ExitOnError)
+	baselinePath := fs.String("baseline", "", "path to the baseline configuration, as returned by GET /config")
+	output := fs.String("o", "text", "output format, one of: text, json")
+	compact := fs.Bool("compact", false, "write -o json without indentation")
+	fs.Parse(args)
+
+	if *baselinePath == "" {
+		return errors.New("missing baseline, use -baseline")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	data, err := os.ReadFile(*baselinePath)
+	if err != nil {
+		return err
+	}
+	var baseline interface{}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return fmt.Errorf("unable to parse baseline %s: %w", *baselinePath, err)
+	}
+
+	resp, err := c.Daemon.GetConfig(daemon.NewGetConfigParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	// Round-trip the live configuration through JSON so that both sides
+	// are made of the same generic types.
+	data, err = json.Marshal(resp.Payload)
+	if err != nil {
+		return err
+	}
+	var live interface{}
+	if err := json.Unmarshal(data, &live); err != nil {
+		return err
+	}
+
+	diff := diffConfigs(flattenConfig(baseline), flattenConfig(live))
+	if *output == "json" {
+		diff.Context = *contextName
+		return writeJSON(rawOutput(out), diff, *compact)
+	}
+	printConfigDiff(out, diff)
+	return nil
+}
+
+// flattenConfig returns the leaf values of a decoded JSON document keyed by
+// their dotted path. Values are normalized to their string form so that e.g.
+// "true" and true compare equal, and lists are kept whole as compact JSON.
+func flattenConfig(v interface{}) map[string]string {
+	res := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				walk(k, child)
+			}
+		case []interface{}:
+			b, _ := json.Marshal(v)
+			res[prefix] = string(b)
+		case float64:
+			res[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+			// Unset values are omitted by the agent, treat explicit
+			// nulls in the baseline the same way.
+		default:
+			res[prefix] = fmt.Sprint(v)
+		}
+	}
+	walk("", v)
+	return res
+}
+
+// diffConfigs returns the keys added to, removed from and changed in live
+// compared to baseline.
+func diffConfigs(baseline, live map[string]string) configDiff {
+	diff := configDiff{
+		Added:   make(map[string]string),
+		Removed: make(map[string]string),
+		Changed: make(map[string]configChange),
+	}
+	for k, v := range live {
+		old, ok := baseline[k]
+		switch {
+		case !ok:
+			diff.Added[k] = v
+		case old != v:
+			diff.Changed[k] = configChange{Baseline: old, Live: v}
+		}
+	}
+	for k, v := range baseline {
+		if _, ok := live[k]; !ok {
+			diff.Removed[k] = v
+		}
+	}
+	return diff
+}
+
+// printConfigDiff prints the removed, added and changed keys, each sorted.
+func printConfigDiff(w io.Writer, diff configDiff) {
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+	for _, k := range sortedKeys(diff.Removed) {
+		fmt.Fprintf(w, "- %s: %s\n", k, diff.Removed[k])
+	}
+	for _, k := range sortedKeys(diff.Added) {
+		fmt.Fprintf(w, "+ %s: %s\n", k, diff.Added[k])
+	}
+	changed := make([]string, 0, len(diff.Changed))
+	for k := range diff.Changed {
+		changed = append(changed, k)
+	}
+	sort.Strings(changed)
+	for _, k := range changed {
+		fmt.Fprintf(w, "~ %s: %s -> %s\n", k, diff.Changed[k].Baseline, diff.Changed[k].Live)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]string
+	}{
+		{"empty", `{}`, map[string]string{}},
+		{"nested", `{"status":{"immutable":{"Debug":"true"}}}`, map[string]string{
+			"status.immutable.Debug": "true",
+		}},
+		{"bool and string compare equal", `{"a":true,"b":"true"}`, map[string]string{
+			"a": "true",
+			"b": "true",
+		}},
+		{"number", `{"mtu":1500,"ratio":0.5}`, map[string]string{
+			"mtu":   "1500",
+			"ratio": "0.5",
+		}},
+		{"list kept whole", `{"cidrs":["10.0.0.0/8", "192.168.0.0/16"]}`, map[string]string{
+			"cidrs": `["10.0.0.0/8","192.168.0.0/16"]`,
+		}},
+		{"null omitted", `{"a":null,"b":{"c":null}}`, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.doc), &v); err != nil {
+				t.Fatal(err)
+			}
+			if got := flattenConfig(v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flattenConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	baseline := map[string]string{"a": "1", "b": "2", "c": "3"}
+	live := map[string]string{"a": "1", "b": "20", "d": "4"}
+	got := diffConfigs(baseline, live)
+	want := configDiff{
+		Added:   map[string]string{"d": "4"},
+		Removed: map[string]string{"c": "3"},
+		Changed: map[string]configChange{"b": {Baseline: "2", Live: "20"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffConfigs() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	printConfigDiff(&buf, got)
+	wantText := "- c: 3\n+ d: 4\n~ b: 2 -> 20\n"
+	if buf.String() != wantText {
+		t.Errorf("printConfigDiff() = %q, want %q", buf.String(), wantText)
+	}
+
+	buf.Reset()
+	printConfigDiff(&buf, diffConfigs(baseline, baseline))
+	if buf.String() != "No differences\n" {
+		t.Errorf("printConfigDiff() = %q, want %q", buf.String(), "No differences\n")
+	}
+}
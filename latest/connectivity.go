@@ -0,0 +1,131 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+
+	"github.com/go-openapi/strfmt"
+)
+
+func init() {
+	register(&command{
+		name:     "connectivity",
+		usage:    "tell whether the policy allows traffic between two endpoints (-from ID -to ID [-dport 80/TCP,...])",
+		run:      runConnectivity,
+		readOnly: true,
+	})
+}
+
+func runConnectivity(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("connectivity", flag.ExitOnError)
+	from := fs.String("from", "", "ID of the source endpoint")
+	to := fs.String("to", "", "ID of the destination endpoint")
+	dports := fs.String("dport", "", "comma separated list of destination ports in the form PORT[/PROTOCOL], each checked on its own")
+	verbose := fs.Bool("verbose", false, "print the policy trace, including the matching rules")
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		return errors.New("missing endpoint IDs, use -from and -to")
+	}
+	ports, err := parsePorts(*dports)
+	if err != nil {
+		return err
+	}
+
+	src, err := getEndpoint(ctx, c, *from)
+	if err != nil {
+		return err
+	}
+	dst, err := getEndpoint(ctx, c, *to)
+	if err != nil {
+		return err
+	}
+
+	// Without ports, the verdict covers L3 only.
+	if len(ports) == 0 {
+		ports = []*models.Port{nil}
+	}
+	for _, port := range ports {
+		sel := &models.TraceSelector{
+			From:    &models.TraceFrom{Labels: endpointLabels(src).GetModel()},
+			To:      &models.TraceTo{Labels: endpointLabels(dst).GetModel()},
+			Verbose: *verbose,
+		}
+		target := fmt.Sprintf("endpoint %s", *to)
+		if port != nil {
+			sel.To.Dports = []*models.Port{port}
+			target += fmt.Sprintf(" port %d/%s", port.Port, port.Protocol)
+		}
+		resp, err := c.Policy.GetPolicyResolve(policy.NewGetPolicyResolveParamsWithContext(ctx).WithTraceSelector(sel))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "From endpoint %s to %s: %s\n", *from, target, colorVerdict(resp.Payload.Verdict))
+		if *verbose && resp.Payload.Log != "" {
+			fmt.Fprintln(out, resp.Payload.Log)
+		}
+	}
+	return nil
+}
+
+// parsePorts parses a comma separated list of ports in the form
+// PORT[/PROTOCOL]. The protocol defaults to ANY.
+func parsePorts(str string) ([]*models.Port, error) {
+	var ports []*models.Port
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		port, proto := s, models.PortProtocolANY
+		if i := strings.Index(s, "/"); i >= 0 {
+			port, proto = s[:i], strings.ToUpper(s[i+1:])
+		}
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", s, err)
+		}
+		p := &models.Port{Port: uint16(n), Protocol: proto}
+		if err := p.Validate(strfmt.Default); err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", s, err)
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// colorVerdict colors a policy verdict: green if the traffic is allowed, red
+// if it is denied.
+func colorVerdict(verdict string) string {
+	switch verdict {
+	case "allowed":
+		return colorize(colorGreen, verdict)
+	case "denied":
+		return colorize(colorRed, verdict)
+	}
+	return colorize(colorYellow, verdict)
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    []*models.Port
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"default protocol", "80", []*models.Port{
+			{Port: 80, Protocol: models.PortProtocolANY},
+		}, false},
+		{"list", "80/tcp, 53/UDP,", []*models.Port{
+			{Port: 80, Protocol: models.PortProtocolTCP},
+			{Port: 53, Protocol: models.PortProtocolUDP},
+		}, false},
+		{"not a number", "http", nil, true},
+		{"out of range", "65536", nil, true},
+		{"unknown protocol", "80/sctp6", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePorts(tt.str)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorVerdict(t *testing.T) {
+	defer func(enabled bool) { colorEnabled = enabled }(colorEnabled)
+	colorEnabled = true
+	tests := []struct {
+		verdict string
+		want    string
+	}{
+		{"allowed", colorGreen + "allowed" + colorReset},
+		{"denied", colorRed + "denied" + colorReset},
+		{"undecided", colorYellow + "undecided" + colorReset},
+	}
+	for _, tt := range tests {
+		if got := colorVerdict(tt.verdict); got != tt.want {
+			t.Errorf("colorVerdict(%q) = %q, want %q", tt.verdict, got, tt.want)
+		}
+	}
+}
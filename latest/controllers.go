@@ -0,0 +1,131 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "controllers",
+		usage:    "list the controllers of the agent and their failures (-only-errors, -o json)",
+		run:      runControllers,
+		readOnly: true,
+	})
+}
+
+func runControllers(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("controllers", flag.ExitOnError)
+	onlyErrors := fs.Bool("only-errors", false, "only list controllers which failed on their last run")
+	output := fs.String("o", "text", "output format, one of: text, json")
+	compact := fs.Bool("compact", false, "write -o json without indentation")
+	fs.Parse(args)
+
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	sr, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	ctrls := sr.Payload.Controllers
+	if *onlyErrors {
+		ctrls = failingControllers(ctrls)
+	}
+	sort.Slice(ctrls, func(i, j int) bool {
+		return ctrls[i].Name < ctrls[j].Name
+	})
+
+	if *output == "json" {
+		res := make([]contextController, 0, len(ctrls))
+		for _, ctrl := range ctrls {
+			res = append(res, contextController{*contextName, ctrl})
+		}
+		return writeJSON(rawOutput(out), res, *compact)
+	}
+	if *onlyErrors && len(ctrls) == 0 {
+		fmt.Fprintln(out, "All controllers healthy")
+		return nil
+	}
+	printControllers(out, ctrls)
+	return nil
+}
+
+// contextController is a controller along with the name given with -context,
+// as written in JSON output.
+type contextController struct {
+	Context string `json:"context,omitempty"`
+	*models.ControllerStatus
+}
+
+// failingControllers returns the controllers with consecutive failures, i.e.
+// whose last run failed.
+func failingControllers(ctrls models.ControllerStatuses) models.ControllerStatuses {
+	var failing models.ControllerStatuses
+	for _, ctrl := range ctrls {
+		if ctrl.Status != nil && ctrl.Status.ConsecutiveFailureCount > 0 {
+			failing = append(failing, ctrl)
+		}
+	}
+	return failing
+}
+
+func printControllers(w io.Writer, ctrls models.ControllerStatuses) {
+	const consecutiveHeader = "CONSECUTIVE"
+	statuses := make([]*models.ControllerStatusStatus, len(ctrls))
+	width := len(consecutiveHeader)
+	for i, ctrl := range ctrls {
+		statuses[i] = ctrl.Status
+		if statuses[i] == nil {
+			statuses[i] = &models.ControllerStatusStatus{}
+		}
+		if n := len(fmt.Sprint(statuses[i].ConsecutiveFailureCount)); n > width {
+			width = n
+		}
+	}
+	// The consecutive failures may be colored. text/tabwriter would count
+	// the escape sequences in the width of the column, so it is padded here
+	// and written along with the last column, which tabwriter leaves as is.
+	width += 3
+
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	printHeader(tw, fmt.Sprintf("NAME\tSUCCESSES\tFAILURES\t%-*sLAST FAILURE", width, consecutiveHeader))
+	for i, ctrl := range ctrls {
+		status := statuses[i]
+		consecutive := fmt.Sprintf("%-*d", width, status.ConsecutiveFailureCount)
+		if status.ConsecutiveFailureCount > 0 {
+			consecutive = colorize(colorRed, consecutive)
+		}
+		lastFailure := "-"
+		if status.LastFailureMsg != "" {
+			lastFailure = status.LastFailureMsg
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s%s\n", ctrl.Name, status.SuccessCount, status.FailureCount, consecutive, lastFailure)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func testControllers() models.ControllerStatuses {
+	return models.ControllerStatuses{
+		{Name: "sync-to-k8s-ciliumendpoint (1234)", Status: &models.ControllerStatusStatus{SuccessCount: 10}},
+		{Name: "resolve-identity-1234", Status: &models.ControllerStatusStatus{
+			SuccessCount:            3,
+			FailureCount:            12,
+			ConsecutiveFailureCount: 12,
+			LastFailureMsg:          "unable to resolve identity",
+		}},
+		{Name: "no-status"},
+	}
+}
+
+func TestFailingControllers(t *testing.T) {
+	failing := failingControllers(testControllers())
+	if len(failing) != 1 || failing[0].Name != "resolve-identity-1234" {
+		t.Errorf("failingControllers() = %v, want resolve-identity-1234 only", failing)
+	}
+}
+
+var ansiEscapeRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestPrintControllersAligned(t *testing.T) {
+	defer func(old bool) { colorEnabled = old }(colorEnabled)
+	colorEnabled = true
+
+	var buf bytes.Buffer
+	printControllers(&buf, testControllers())
+	if !strings.Contains(buf.String(), colorRed) {
+		t.Fatalf("failing controller not colored:\n%s", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(ansiEscapeRe.ReplaceAllString(buf.String(), ""), "\n"), "\n")
+	col := strings.Index(lines[0], "LAST FAILURE")
+	for _, line := range lines[1:] {
+		if len(line) <= col || line[col-1] != ' ' || line[col] == ' ' {
+			t.Errorf("last failure column not aligned at %d:\n%s", col, strings.Join(lines, "\n"))
+			break
+		}
+	}
+}
+
+func TestControllersJSONContext(t *testing.T) {
+	defer func(old string) { *contextName = old }(*contextName)
+	*contextName = "prod"
+
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{Controllers: testControllers()})
+
+	var buf bytes.Buffer
+	if err := runControllers(context.Background(), c, &buf, []string{"-o", "json", "-only-errors"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, buf.String())
+	}
+	if len(got) != 1 || got[0]["context"] != "prod" || got[0]["name"] != "resolve-identity-1234" {
+		t.Errorf("runControllers() = %v, want the failing controller with context prod", got)
+	}
+}
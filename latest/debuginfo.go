@@ -0,0 +1,93 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "debuginfo",
+		usage:    "dump the agent's debug information as JSON",
+		run:      runDebuginfo,
+		readOnly: true,
+	})
+}
+
+func runDebuginfo(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("debuginfo", flag.ExitOnError)
+	// Not named -out as the global flag, which applies to the output of
+	// every command, -sockets headers included.
+	file := fs.String("file", "", "file to write the debug information to instead of the command output, compressed if it ends in .gz")
+	redact := fs.Bool("redact", false, "redact the values of environment variables")
+	compact := fs.Bool("compact", false, "write the JSON without indentation, to make large dumps smaller")
+	fs.Parse(args)
+
+	if *file != "" && *sockets != "" {
+		// The agents are queried concurrently and would all write to the
+		// same file.
+		return errors.New("-file cannot be used with -sockets, use the global -out instead")
+	}
+
+	resp, err := c.Daemon.GetDebuginfo(daemon.NewGetDebuginfoParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	info := resp.Payload
+	if *redact {
+		redactDebugInfo(info)
+	}
+
+	if *file == "" {
+		return writeDebugInfo(rawOutput(out), info, *compact)
+	}
+	f, err := openOutput(*file)
+	if err != nil {
+		return err
+	}
+	if err := writeDebugInfo(f, info, *compact); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeDebugInfo(w io.Writer, info *models.DebugInfo, compact bool) error {
+	return writeJSON(w, struct {
+		Context string `json:"context,omitempty"`
+		*models.DebugInfo
+	}{*contextName, info}, compact)
+}
+
+// redactDebugInfo strips the values of the agent's environment variables,
+// which may hold credentials, keeping only their names.
+func redactDebugInfo(info *models.DebugInfo) {
+	for i, env := range info.EnvironmentVariables {
+		if j := strings.Index(env, "="); j >= 0 {
+			info.EnvironmentVariables[i] = env[:j+1] + "<redacted>"
+		}
+	}
+}
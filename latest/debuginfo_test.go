@@ -0,0 +1,73 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestDebuginfoFile(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /debuginfo", http.StatusOK, &models.DebugInfo{
+		EnvironmentVariables: []string{"K8S_TOKEN=secret"},
+	})
+
+	path := filepath.Join(t.TempDir(), "debuginfo.json.gz")
+	var out bytes.Buffer
+	if err := runDebuginfo(context.Background(), c, &out, []string{"-file", path, "-redact"}); err != nil {
+		t.Fatalf("runDebuginfo() = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("debug information written to the command output too:\n%s", out.String())
+	}
+	r, err := openInput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var info models.DebugInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if got := info.EnvironmentVariables; len(got) != 1 || got[0] != "K8S_TOKEN=<redacted>" {
+		t.Errorf("environment variables = %q, want redacted", got)
+	}
+}
+
+func TestDebuginfoFileWithSockets(t *testing.T) {
+	defer func(old string) { *sockets = old }(*sockets)
+	*sockets = "/var/run/a.sock,/var/run/b.sock"
+
+	path := filepath.Join(t.TempDir(), "debuginfo.json")
+	// The command must fail before making any API call, so no client is
+	// needed.
+	err := runDebuginfo(context.Background(), nil, &bytes.Buffer{}, []string{"-file", path})
+	if err == nil {
+		t.Fatal("runDebuginfo() succeeded with -file and -sockets")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("debug information file written: %v", err)
+	}
+}
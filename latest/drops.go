@@ -0,0 +1,170 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/metrics"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// dropCountMetric is the agent metric counting dropped packets by reason and
+// direction.
+const dropCountMetric = "cilium_drop_count_total"
+
+func init() {
+	register(&command{
+		name:     "drops",
+		usage:    "rank the reasons packets are dropped for, from the agent metrics (-top N)",
+		run:      runDrops,
+		readOnly: true,
+	})
+}
+
+// dropCount is the number of packets dropped for a reason in a direction.
+type dropCount struct {
+	reason    string
+	direction string
+	packets   float64
+}
+
+func runDrops(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("drops", flag.ExitOnError)
+	top := fs.Int("top", 10, "number of drop reasons to print, all if 0")
+	url := fs.String("url", "", "scrape the Prometheus metrics endpoint of the agent at this URL instead of using the API, e.g. http://localhost:9962/metrics")
+	fs.Parse(args)
+
+	var ms []*models.Metric
+	if *url != "" {
+		var err error
+		if ms, err = scrapeMetrics(ctx, *url); err != nil {
+			return err
+		}
+	} else {
+		resp, err := c.Metrics.GetMetrics(metrics.NewGetMetricsParamsWithContext(ctx))
+		if err != nil {
+			return err
+		}
+		ms = resp.Payload
+	}
+
+	drops := aggregateDrops(ms)
+	if *top > 0 && len(drops) > *top {
+		drops = drops[:*top]
+	}
+	printDrops(out, drops)
+	return nil
+}
+
+// scrapeMetrics fetches the metrics in the Prometheus text format from url.
+func scrapeMetrics(ctx context.Context, url string) ([]*models.Metric, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to scrape %s: %s", url, resp.Status)
+	}
+	return parseMetricsText(resp.Body)
+}
+
+// parseMetricsText parses metrics in the Prometheus text format into the
+// form returned by the API. Only counters and gauges are kept.
+func parseMetricsText(r io.Reader) ([]*models.Metric, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse metrics: %w", err)
+	}
+	var ms []*models.Metric
+	for name, family := range families {
+		for _, m := range family.GetMetric() {
+			var value float64
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				value = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				value = m.GetGauge().GetValue()
+			default:
+				continue
+			}
+			lbls := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				lbls[l.GetName()] = l.GetValue()
+			}
+			ms = append(ms, &models.Metric{Name: name, Labels: lbls, Value: value})
+		}
+	}
+	return ms, nil
+}
+
+// aggregateDrops sums the drop counters by reason and direction, and returns
+// them ranked by the number of dropped packets.
+func aggregateDrops(ms []*models.Metric) []dropCount {
+	type key struct{ reason, direction string }
+	sums := make(map[key]float64)
+	for _, m := range ms {
+		if m.Name != dropCountMetric {
+			continue
+		}
+		sums[key{m.Labels["reason"], m.Labels["direction"]}] += m.Value
+	}
+
+	drops := make([]dropCount, 0, len(sums))
+	for k, v := range sums {
+		drops = append(drops, dropCount{reason: k.reason, direction: k.direction, packets: v})
+	}
+	sort.Slice(drops, func(i, j int) bool {
+		if drops[i].packets != drops[j].packets {
+			return drops[i].packets > drops[j].packets
+		}
+		if drops[i].reason != drops[j].reason {
+			return drops[i].reason < drops[j].reason
+		}
+		return drops[i].direction < drops[j].direction
+	})
+	return drops
+}
+
+func printDrops(w io.Writer, drops []dropCount) {
+	if len(drops) == 0 {
+		fmt.Fprintln(w, "No dropped packets")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	printHeader(tw, "RANK\tREASON\tDIRECTION\tPACKETS")
+	for i, d := range drops {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.0f\n", i+1, d.reason, d.direction, d.packets)
+	}
+	tw.Flush()
+}
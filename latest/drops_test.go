@@ -0,0 +1,88 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dropsMetricsText = `# TYPE cilium_drop_count_total counter
+cilium_drop_count_total{direction="INGRESS",reason="Policy denied"} 10
+cilium_drop_count_total{direction="EGRESS",reason="Policy denied"} 3
+cilium_drop_count_total{direction="INGRESS",reason="Invalid packet"} 4
+cilium_drop_count_total{direction="INGRESS",reason="Stale or unroutable IP"} 4
+# TYPE cilium_endpoint_count gauge
+cilium_endpoint_count 7
+# TYPE cilium_api_duration_seconds histogram
+cilium_api_duration_seconds_bucket{le="+Inf"} 1
+cilium_api_duration_seconds_sum 0.1
+cilium_api_duration_seconds_count 1
+`
+
+func TestAggregateDrops(t *testing.T) {
+	ms, err := parseMetricsText(strings.NewReader(dropsMetricsText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 5 {
+		t.Errorf("parseMetricsText() returned %d metrics, want 5 (histograms skipped)", len(ms))
+	}
+
+	// Duplicate series, as from several agents, are summed.
+	for _, m := range ms {
+		if m.Labels["direction"] == "INGRESS" && m.Labels["reason"] == "Policy denied" {
+			ms = append(ms, m)
+			break
+		}
+	}
+	got := aggregateDrops(ms)
+	want := []dropCount{
+		{reason: "Policy denied", direction: "INGRESS", packets: 20},
+		{reason: "Invalid packet", direction: "INGRESS", packets: 4},
+		{reason: "Stale or unroutable IP", direction: "INGRESS", packets: 4},
+		{reason: "Policy denied", direction: "EGRESS", packets: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregateDrops() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	printDrops(&buf, got[:2])
+	wantText := "RANK   REASON           DIRECTION   PACKETS\n" +
+		"1      Policy denied    INGRESS     20\n" +
+		"2      Invalid packet   INGRESS     4\n"
+	if buf.String() != wantText {
+		t.Errorf("printDrops() =\n%s\nwant\n%s", buf.String(), wantText)
+	}
+}
+
+func TestParseMetricsTextInvalid(t *testing.T) {
+	if _, err := parseMetricsText(strings.NewReader("not a metric line {\n")); err == nil {
+		t.Error("parseMetricsText() succeeded on malformed input")
+	}
+}
+
+func TestPrintDropsEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	printDrops(&buf, aggregateDrops(nil))
+	if buf.String() != "No dropped packets\n" {
+		t.Errorf("printDrops() = %q, want %q", buf.String(), "No dropped packets\n")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "endpoint-bpf",
+		usage:    "show the datapath health and policy revisions of an endpoint (-id N | -interactive)",
+		run:      runEndpointBPF,
+		readOnly: true,
+	})
+}
+
+// endpointRevisions are the policy revisions of an endpoint. The datapath
+// lags behind when the realized revision is lower than the desired one.
+type endpointRevisions struct {
+	desired  int64
+	realized int64
+	proxy    int64
+}
+
+func newEndpointRevisions(ep *models.Endpoint) endpointRevisions {
+	var r endpointRevisions
+	if ep.Status == nil || ep.Status.Policy == nil {
+		return r
+	}
+	p := ep.Status.Policy
+	if p.Spec != nil {
+		r.desired = p.Spec.PolicyRevision
+	}
+	if p.Realized != nil {
+		r.realized = p.Realized.PolicyRevision
+	}
+	r.proxy = p.ProxyPolicyRevision
+	return r
+}
+
+// lag returns by how many revisions the datapath is behind the desired
+// policy.
+func (r endpointRevisions) lag() int64 {
+	if r.realized >= r.desired {
+		return 0
+	}
+	return r.desired - r.realized
+}
+
+func runEndpointBPF(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-bpf", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if -id is not given")
+	fs.Parse(args)
+
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if *id == "" {
+		return errors.New("missing endpoint ID, use -id or -interactive")
+	}
+	ep, err := getEndpoint(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	printEndpointBPF(out, ep)
+	return nil
+}
+
+// printEndpointBPF prints the datapath details of ep. The API does not
+// expose the IDs of the BPF maps of an endpoint, only the health of its
+// programs and the policy revisions they implement.
+func printEndpointBPF(w io.Writer, ep *models.Endpoint) {
+	bpf, policy := "unknown", "unknown"
+	if ep.Status != nil && ep.Status.Health != nil {
+		bpf, policy = string(ep.Status.Health.Bpf), string(ep.Status.Health.Policy)
+	}
+	r := newEndpointRevisions(ep)
+
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "Endpoint:\t%d\n", ep.ID)
+	fmt.Fprintf(tw, "State:\t%s\n", colorState(endpointState(ep)))
+	fmt.Fprintf(tw, "BPF programs:\t%s\n", colorState(bpf))
+	fmt.Fprintf(tw, "Policy:\t%s\n", colorState(policy))
+	fmt.Fprintf(tw, "Desired policy revision:\t%d\n", r.desired)
+	if lag := r.lag(); lag > 0 {
+		fmt.Fprintf(tw, "Datapath policy revision:\t%s\n",
+			colorize(colorYellow, fmt.Sprintf("%d (%d behind)", r.realized, lag)))
+	} else {
+		fmt.Fprintf(tw, "Datapath policy revision:\t%s\n",
+			colorize(colorGreen, fmt.Sprintf("%d (up to date)", r.realized)))
+	}
+	fmt.Fprintf(tw, "Proxy policy revision:\t%d\n", r.proxy)
+	tw.Flush()
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+// withPolicyRevisions sets the desired, realized and proxy policy revisions
+// of ep.
+func withPolicyRevisions(ep *models.Endpoint, desired, realized, proxy int64) *models.Endpoint {
+	ep.Status.Policy = &models.EndpointPolicyStatus{
+		Spec:                &models.EndpointPolicy{PolicyRevision: desired},
+		Realized:            &models.EndpointPolicy{PolicyRevision: realized},
+		ProxyPolicyRevision: proxy,
+	}
+	return ep
+}
+
+func TestEndpointRevisionsLag(t *testing.T) {
+	tests := []struct {
+		name string
+		ep   *models.Endpoint
+		want endpointRevisions
+		lag  int64
+	}{
+		{"no policy", testEndpoint(1, models.EndpointStateReady, 100), endpointRevisions{}, 0},
+		{"up to date", withPolicyRevisions(testEndpoint(1, models.EndpointStateReady, 100), 7, 7, 7),
+			endpointRevisions{desired: 7, realized: 7, proxy: 7}, 0},
+		{"behind", withPolicyRevisions(testEndpoint(1, models.EndpointStateRegenerating, 100), 9, 6, 5),
+			endpointRevisions{desired: 9, realized: 6, proxy: 5}, 3},
+		// The realized revision may briefly be ahead while the desired
+		// one is updated.
+		{"ahead", withPolicyRevisions(testEndpoint(1, models.EndpointStateReady, 100), 6, 7, 7),
+			endpointRevisions{desired: 6, realized: 7, proxy: 7}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newEndpointRevisions(tt.ep)
+			if r != tt.want {
+				t.Errorf("newEndpointRevisions() = %+v, want %+v", r, tt.want)
+			}
+			if got := r.lag(); got != tt.lag {
+				t.Errorf("lag() = %d, want %d", got, tt.lag)
+			}
+		})
+	}
+}
+
+func TestEndpointBPF(t *testing.T) {
+	ep := withPolicyRevisions(testEndpoint(3, models.EndpointStateRegenerating, 100), 9, 6, 5)
+	ep.Status.Health = &models.EndpointHealth{Bpf: models.EndpointHealthStatusOK, Policy: models.EndpointHealthStatusPending}
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint/3", http.StatusOK, ep)
+
+	var out bytes.Buffer
+	if err := runEndpointBPF(context.Background(), c, &out, []string{"-id", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"Endpoint:                   3\n" +
+		"State:                      regenerating\n" +
+		"BPF programs:               OK\n" +
+		"Policy:                     Pending\n" +
+		"Desired policy revision:    9\n" +
+		"Datapath policy revision:   6 (3 behind)\n" +
+		"Proxy policy revision:      5\n"
+	if out.String() != want {
+		t.Errorf("runEndpointBPF() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:  "endpoint-config",
+		usage: "show or change the configuration of an endpoint (-id N [-set KEY=VALUE])",
+		run:   runEndpointConfig,
+	})
+}
+
+func runEndpointConfig(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-config", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if -id is not given")
+	set := fs.String("set", "", "option to change, in the form KEY=VALUE")
+	fs.Parse(args)
+
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if *id == "" {
+		return errors.New("missing endpoint ID, use -id or -interactive")
+	}
+
+	before, err := getEndpointOptions(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	if *set == "" {
+		printEndpointOptions(out, before)
+		return nil
+	}
+
+	key, value, err := parseEndpointOption(*set, before)
+	if err != nil {
+		return err
+	}
+	params := endpoint.NewPatchEndpointIDConfigParamsWithContext(ctx).
+		WithID(*id).
+		WithEndpointConfiguration(&models.EndpointConfigurationSpec{
+			Options: models.ConfigurationMap{key: value},
+		})
+	if _, err := c.Endpoint.PatchEndpointIDConfig(params); err != nil {
+		return err
+	}
+
+	after, err := getEndpointOptions(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	printEndpointOptionsDiff(out, before, after)
+	return nil
+}
+
+// getEndpointOptions returns the changeable options currently applied to the
+// endpoint with the given ID.
+func getEndpointOptions(ctx context.Context, c *client.Client, id string) (models.ConfigurationMap, error) {
+	resp, err := c.Endpoint.GetEndpointIDConfig(endpoint.NewGetEndpointIDConfigParamsWithContext(ctx).WithID(id))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Payload.Realized == nil {
+		return models.ConfigurationMap{}, nil
+	}
+	return resp.Payload.Realized.Options, nil
+}
+
+// parseEndpointOption parses an option of the form KEY=VALUE. The key must
+// be one of the options in known.
+func parseEndpointOption(option string, known models.ConfigurationMap) (key, value string, err error) {
+	i := strings.Index(option, "=")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid option %q, must be of the form KEY=VALUE", option)
+	}
+	key, value = option[:i], option[i+1:]
+	if _, ok := known[key]; !ok {
+		return "", "", fmt.Errorf("unknown option %q, must be one of: %s", key, strings.Join(sortedKeys(known), ", "))
+	}
+	return key, value, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printEndpointOptions(out io.Writer, options models.ConfigurationMap) {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "OPTION\tVALUE")
+	for _, k := range sortedKeys(options) {
+		fmt.Fprintf(w, "%s\t%s\n", k, options[k])
+	}
+	w.Flush()
+}
+
+// printEndpointOptionsDiff prints the options whose values differ between
+// before and after.
+func printEndpointOptionsDiff(out io.Writer, before, after models.ConfigurationMap) {
+	keys := make(map[string]string, len(after))
+	for k := range before {
+		keys[k] = ""
+	}
+	for k := range after {
+		keys[k] = ""
+	}
+
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "OPTION\tBEFORE\tAFTER")
+	changed := 0
+	for _, k := range sortedKeys(keys) {
+		if before[k] != after[k] {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k, before[k], after[k])
+			changed++
+		}
+	}
+	w.Flush()
+	if changed == 0 {
+		fmt.Fprintln(out, "No option changed")
+	}
+}
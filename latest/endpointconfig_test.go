@@ -0,0 +1,95 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestParseEndpointOption(t *testing.T) {
+	known := models.ConfigurationMap{"Debug": "Disabled", "Conntrack": "Enabled"}
+	tests := []struct {
+		option     string
+		key, value string
+		wantErr    string
+	}{
+		{"Debug=Enabled", "Debug", "Enabled", ""},
+		{"Debug=", "Debug", "", ""},
+		{"Debug", "", "", `invalid option "Debug", must be of the form KEY=VALUE`},
+		{"Policy=Enabled", "", "", `unknown option "Policy", must be one of: Conntrack, Debug`},
+	}
+	for _, tt := range tests {
+		key, value, err := parseEndpointOption(tt.option, known)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseEndpointOption(%q) error = %v, want %s", tt.option, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("parseEndpointOption(%q) = %q, %q, %v, want %q, %q", tt.option, key, value, err, tt.key, tt.value)
+		}
+	}
+}
+
+func TestEndpointConfigSet(t *testing.T) {
+	options := models.ConfigurationMap{"Debug": "Disabled", "Conntrack": "Enabled"}
+	agent, c := newFakeAgent(t)
+	agent.handle("GET /endpoint/1/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&models.EndpointConfigurationStatus{
+			Realized: &models.EndpointConfigurationSpec{Options: options},
+		})
+	})
+	var patched models.ConfigurationMap
+	agent.handle("PATCH /endpoint/1/config", func(w http.ResponseWriter, r *http.Request) {
+		var spec models.EndpointConfigurationSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			t.Error(err)
+		}
+		patched = spec.Options
+		options = models.ConfigurationMap{"Debug": "Enabled", "Conntrack": "Enabled"}
+	})
+
+	var out bytes.Buffer
+	if err := runEndpointConfig(context.Background(), c, &out, []string{"-id", "1", "-set", "Debug=Enabled"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.ConfigurationMap{"Debug": "Enabled"}); !reflect.DeepEqual(patched, want) {
+		t.Errorf("patched options = %v, want %v", patched, want)
+	}
+	want := "OPTION   BEFORE     AFTER\nDebug    Disabled   Enabled\n"
+	if out.String() != want {
+		t.Errorf("runEndpointConfig() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrintEndpointOptionsDiff(t *testing.T) {
+	before := models.ConfigurationMap{"Debug": "Enabled"}
+	var buf bytes.Buffer
+	printEndpointOptionsDiff(&buf, before, before)
+	if want := "OPTION   BEFORE   AFTER\nNo option changed\n"; buf.String() != want {
+		t.Errorf("printEndpointOptionsDiff() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:  "endpoint-config-apply",
+		usage: "change the configuration of endpoints as described in a JSON file (-file PATH [-dry-run])",
+		run:   runEndpointConfigApply,
+	})
+}
+
+// endpointConfigEntry is an entry of the file read by endpoint-config-apply.
+// It applies options to the endpoint with the given ID, or to all endpoints
+// matching the label selector, e.g.
+//
+//	[
+//	  {"id": "1234", "options": {"Debug": "Enabled"}},
+//	  {"selector": "k8s:app=web", "options": {"PolicyAuditMode": "Enabled"}}
+//	]
+type endpointConfigEntry struct {
+	ID       string                  `json:"id,omitempty"`
+	Selector string                  `json:"selector,omitempty"`
+	Options  models.ConfigurationMap `json:"options"`
+}
+
+func runEndpointConfigApply(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-config-apply", flag.ExitOnError)
+	file := fs.String("file", "", "JSON file listing the options to apply per endpoint ID or label selector")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	concurrency := fs.Int("concurrency", 4, "number of endpoints changed at the same time")
+	fs.Parse(args)
+
+	if *file == "" {
+		return errors.New("missing file, use -file")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d, must be at least 1", *concurrency)
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	entries, err := parseEndpointConfigEntries(data)
+	if err != nil {
+		return fmt.Errorf("invalid file %s: %w", *file, err)
+	}
+
+	ids, options, err := resolveEndpointConfigEntries(ctx, c, entries)
+	if err != nil {
+		return err
+	}
+
+	// Validate all options against the endpoints before changing any of
+	// them.
+	before := make([]models.ConfigurationMap, len(ids))
+	for i, id := range ids {
+		if before[i], err = getEndpointOptions(ctx, c, id); err != nil {
+			return fmt.Errorf("endpoint %s: %w", id, err)
+		}
+		for key := range options[i] {
+			if _, ok := before[i][key]; !ok {
+				return fmt.Errorf("endpoint %s: unknown option %q, must be one of: %s", id, key, strings.Join(sortedKeys(before[i]), ", "))
+			}
+		}
+	}
+
+	if *dryRun {
+		for i, id := range ids {
+			after := make(models.ConfigurationMap, len(before[i]))
+			for k, v := range before[i] {
+				after[k] = v
+			}
+			for k, v := range options[i] {
+				after[k] = v
+			}
+			fmt.Fprintf(out, "Endpoint %s:\n", id)
+			printEndpointOptionsDiff(out, before[i], after)
+		}
+		return nil
+	}
+
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			params := endpoint.NewPatchEndpointIDConfigParamsWithContext(ctx).
+				WithID(ids[i]).
+				WithEndpointConfiguration(&models.EndpointConfigurationSpec{Options: options[i]})
+			_, errs[i] = c.Endpoint.PatchEndpointIDConfig(params)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(out, "Endpoint %s: failed: %s\n", ids[i], client.Hint(err))
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "Endpoint %s: %d options applied\n", ids[i], len(options[i]))
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to change %d of %d endpoints", failed, len(ids))
+	}
+	return nil
+}
+
+// parseEndpointConfigEntries decodes and validates the entries of an
+// endpoint-config-apply file.
+func parseEndpointConfigEntries(data []byte) ([]endpointConfigEntry, error) {
+	var entries []endpointConfigEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entries); err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if (e.ID == "") == (e.Selector == "") {
+			return nil, fmt.Errorf("entry %d: exactly one of id and selector must be set", i)
+		}
+		if len(e.Options) == 0 {
+			return nil, fmt.Errorf("entry %d: no options", i)
+		}
+	}
+	return entries, nil
+}
+
+// resolveEndpointConfigEntries returns the IDs of the endpoints targeted by
+// entries, sorted, along with the options to apply to each of them. Options
+// of later entries override the ones of earlier entries for the same
+// endpoint. A selector matching no endpoint, e.g. because of a typo, is an
+// error.
+func resolveEndpointConfigEntries(ctx context.Context, c *client.Client, entries []endpointConfigEntry) ([]string, []models.ConfigurationMap, error) {
+	var eps []*models.Endpoint
+	byID := make(map[string]models.ConfigurationMap)
+	for i, e := range entries {
+		targets := []string{e.ID}
+		if e.Selector != "" {
+			expr := parseLabelExpr(e.Selector)
+			if len(expr) == 0 {
+				return nil, nil, fmt.Errorf("entry %d: invalid selector %q, it has no labels", i, e.Selector)
+			}
+			if eps == nil {
+				resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+				if err != nil {
+					return nil, nil, err
+				}
+				eps = resp.Payload
+			}
+			targets = targets[:0]
+			for _, ep := range filterEndpointsByLabels(eps, expr) {
+				targets = append(targets, strconv.FormatInt(ep.ID, 10))
+			}
+			if len(targets) == 0 {
+				return nil, nil, fmt.Errorf("entry %d: selector %q matches no endpoint", i, e.Selector)
+			}
+		}
+		for _, id := range targets {
+			if byID[id] == nil {
+				byID[id] = models.ConfigurationMap{}
+			}
+			for k, v := range e.Options {
+				byID[id][k] = v
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+	options := make([]models.ConfigurationMap, len(ids))
+	for i, id := range ids {
+		options[i] = byID[id]
+	}
+	return ids, options, nil
+}
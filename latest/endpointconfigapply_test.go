@@ -0,0 +1,102 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestParseEndpointConfigEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"id", `[{"id": "1234", "options": {"Debug": "Enabled"}}]`, ""},
+		{"selector", `[{"selector": "k8s:app=web", "options": {"Debug": "Enabled"}}]`, ""},
+		{"malformed", `[{"id": "1234", "options": {"Debug": "Enabled"}`, "unexpected EOF"},
+		{"unknown field", `[{"id": "1234", "option": {"Debug": "Enabled"}}]`, "unknown field"},
+		{"id and selector", `[{"id": "1", "selector": "k8s:app=web", "options": {"Debug": "Enabled"}}]`, "entry 0: exactly one"},
+		{"neither id nor selector", `[{"options": {"Debug": "Enabled"}}]`, "entry 0: exactly one"},
+		{"no options", `[{"id": "1"}, {"id": "2", "options": {}}]`, "entry 0: no options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseEndpointConfigEntries([]byte(tt.data))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("parseEndpointConfigEntries() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("parseEndpointConfigEntries() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEndpointConfigApplyValidatesFirst(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"malformed file", `[{"selector": "k8s:app=web"`, "invalid file"},
+		{"selector matching nothing", `[
+			{"selector": "k8s:app=web", "options": {"Debug": "Enabled"}},
+			{"selector": "k8s:app=wbe", "options": {"Debug": "Enabled"}}
+		]`, `entry 1: selector "k8s:app=wbe" matches no endpoint`},
+		{"selector without labels", `[
+			{"selector": "k8s:app=web", "options": {"Debug": "Enabled"}},
+			{"selector": ",|", "options": {"Debug": "Enabled"}}
+		]`, `entry 1: invalid selector ",|", it has no labels`},
+		{"unknown option", `[{"id": "1", "options": {"Debgu": "Enabled"}}]`, `unknown option "Debgu"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, c := newFakeAgent(t)
+			agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+				testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+			})
+			agent.respond("GET /endpoint/1/config", http.StatusOK, &models.EndpointConfigurationStatus{
+				Realized: &models.EndpointConfigurationSpec{Options: models.ConfigurationMap{"Debug": "Disabled"}},
+			})
+			agent.respond("PATCH /endpoint/1/config", http.StatusOK, nil)
+
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := runEndpointConfigApply(context.Background(), c, &bytes.Buffer{}, []string{"-file", path})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runEndpointConfigApply() = %v, want error containing %q", err, tt.wantErr)
+			}
+			if n := agent.callCount("PATCH /endpoint/1/config"); n != 0 {
+				t.Errorf("endpoint changed %d times despite the invalid file", n)
+			}
+			if tt.name == "malformed file" && agent.callCount("GET /endpoint") != 0 {
+				t.Error("agent queried before the file was validated")
+			}
+		})
+	}
+}
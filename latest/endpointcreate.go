@@ -0,0 +1,89 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+
+	"github.com/go-openapi/strfmt"
+)
+
+func init() {
+	register(&command{
+		name:  "endpoint-create",
+		usage: "create an endpoint (-container-id ID [-l k8s:app=foo,...])",
+		run:   runEndpointCreate,
+	})
+}
+
+func runEndpointCreate(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-create", flag.ExitOnError)
+	containerID := fs.String("container-id", "", "ID of the container the endpoint belongs to")
+	lblsArg := fs.String("l", "", "comma separated list of labels in the form SOURCE:KEY=VALUE")
+	fs.Parse(args)
+
+	req, err := newEndpointChangeRequest(*containerID, *lblsArg)
+	if err != nil {
+		return err
+	}
+	// The agent picks the ID of the new endpoint when it is created with ID
+	// 0, as the CNI plugin does.
+	params := endpoint.NewPutEndpointIDParamsWithContext(ctx).WithID("0").WithEndpoint(req)
+	if _, err := c.Endpoint.PutEndpointID(params); err != nil {
+		return err
+	}
+
+	// The response does not carry the endpoint, look it up by container ID.
+	ep, err := getEndpoint(ctx, c, "container-id:"+req.ContainerID)
+	if err != nil {
+		return fmt.Errorf("endpoint created but not found: %w", err)
+	}
+	fmt.Fprintf(out, "Created endpoint %d\n", ep.ID)
+	return nil
+}
+
+// newEndpointChangeRequest returns the minimal request needed to create an
+// endpoint for the given container.
+func newEndpointChangeRequest(containerID, lblsArg string) (*models.EndpointChangeRequest, error) {
+	if containerID == "" {
+		return nil, errors.New("missing container ID, use -container-id")
+	}
+	req := &models.EndpointChangeRequest{
+		ContainerID: containerID,
+		State:       models.EndpointStateWaitingForIdentity,
+	}
+	if lblsArg != "" {
+		lbls, err := parseLabelArgs(strings.Split(lblsArg, ","))
+		if err != nil {
+			return nil, err
+		}
+		req.Labels = lbls.GetModel()
+	}
+	if err := req.Validate(strfmt.Default); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestNewEndpointChangeRequest(t *testing.T) {
+	captureLogs(t)
+	tests := []struct {
+		name        string
+		containerID string
+		lbls        string
+		wantLabels  []string
+		wantErr     bool
+	}{
+		{"no labels", "abc", "", nil, false},
+		{"labels", "abc", "k8s:app=web,$host", []string{"k8s:app=web", "reserved:host"}, false},
+		{"missing container ID", "", "k8s:app=web", nil, true},
+		{"invalid label", "abc", "k8s:app=web;db", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := newEndpointChangeRequest(tt.containerID, tt.lbls)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newEndpointChangeRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if req.ContainerID != tt.containerID || req.State != models.EndpointStateWaitingForIdentity {
+				t.Errorf("newEndpointChangeRequest() = %+v", req)
+			}
+			got := []string(req.Labels)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantLabels) {
+				t.Errorf("newEndpointChangeRequest() labels = %v, want %v", got, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestEndpointCreate(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	var created models.EndpointChangeRequest
+	agent.handle("PUT /endpoint/0", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	agent.respond("GET /endpoint/container-id:abc", http.StatusOK, testEndpoint(42, models.EndpointStateWaitingForIdentity, 0))
+
+	var out bytes.Buffer
+	if err := runEndpointCreate(context.Background(), c, &out, []string{"-container-id", "abc", "-l", "k8s:app=web"}); err != nil {
+		t.Fatal(err)
+	}
+	if created.ContainerID != "abc" || !reflect.DeepEqual([]string(created.Labels), []string{"k8s:app=web"}) {
+		t.Errorf("created endpoint %+v", created)
+	}
+	if want := "Created endpoint 42\n"; out.String() != want {
+		t.Errorf("runEndpointCreate() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestEndpointCreateNotFound(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("PUT /endpoint/0", http.StatusCreated, nil)
+	agent.respond("GET /endpoint/container-id:abc", http.StatusNotFound, nil)
+
+	var out bytes.Buffer
+	err := runEndpointCreate(context.Background(), c, &out, []string{"-container-id", "abc"})
+	if err == nil || !strings.HasPrefix(err.Error(), "endpoint created but not found: ") {
+		t.Errorf("runEndpointCreate() error = %v, want the endpoint not to be found", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:  "endpoint-delete",
+		usage: "delete an endpoint, or all endpoints matching a selector (-id N | -l SELECTORS | -interactive [-yes])",
+		run:   runEndpointDelete,
+	})
+}
+
+func runEndpointDelete(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-delete", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint to delete")
+	selector := fs.String("l", "", "delete all endpoints matching these comma separated label selectors, or any of the groups of selectors separated by |")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if neither -id nor -l is given")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	fs.Parse(args)
+
+	if *selector != "" && *id != "" {
+		return errors.New("use either -id or -l")
+	}
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive && *selector == "")
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if (*id == "") == (*selector == "") {
+		return errors.New("use either -id, -l or -interactive")
+	}
+	// An expression without any selector matches all endpoints, which is
+	// never what is meant here.
+	expr := parseLabelExpr(*selector)
+	if *selector != "" && len(expr) == 0 {
+		return fmt.Errorf("invalid selector %q, it has no labels", *selector)
+	}
+
+	var eps []*models.Endpoint
+	if *id != "" {
+		ep, err := getEndpoint(ctx, c, *id)
+		if err != nil {
+			return err
+		}
+		eps = []*models.Endpoint{ep}
+	} else {
+		resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+		if err != nil {
+			return err
+		}
+		eps = filterEndpointsByLabels(resp.Payload, expr)
+		if len(eps) == 0 {
+			fmt.Fprintf(out, "No endpoint matches %s\n", *selector)
+			return nil
+		}
+		sortEndpoints(eps, endpointSortKeys["id"], false)
+	}
+
+	if !*yes {
+		for _, ep := range eps {
+			fmt.Fprintf(os.Stderr, "%d\t%s\n", ep.ID, endpointName(ep))
+		}
+		if !confirm(os.Stdin, os.Stderr, fmt.Sprintf("Delete %d endpoints?", len(eps))) {
+			fmt.Fprintln(out, "No endpoint deleted")
+			return nil
+		}
+		// The time spent confirming does not count against -timeout.
+		var cancel context.CancelFunc
+		ctx, cancel = renewTimeout(ctx)
+		defer cancel()
+	}
+
+	failed := 0
+	for _, ep := range eps {
+		if err := deleteEndpoint(ctx, c, ep.ID); err != nil {
+			fmt.Fprintf(out, "Unable to delete endpoint %d: %s\n", ep.ID, client.Hint(err))
+			failed++
+		}
+	}
+	fmt.Fprintf(out, "Deleted %d of %d endpoints\n", len(eps)-failed, len(eps))
+	if failed > 0 {
+		return fmt.Errorf("unable to delete %d endpoints", failed)
+	}
+	return nil
+}
+
+// deleteEndpoint deletes the endpoint with the given ID. The agent may
+// delete the endpoint but fail to clean up some of its state, which is
+// reported as an error as well.
+func deleteEndpoint(ctx context.Context, c *client.Client, id int64) error {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	params := endpoint.NewDeleteEndpointIDParamsWithContext(ctx).WithID(strconv.FormatInt(id, 10))
+	_, partial, err := c.Endpoint.DeleteEndpointID(params)
+	if err != nil {
+		return err
+	}
+	if partial != nil {
+		return fmt.Errorf("deleted with %d errors", partial.Payload)
+	}
+	return nil
+}
+
+// confirm asks the question on w and returns true if the answer read from r
+// is yes.
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// fakeDeleteAgent returns a fake agent with a web endpoint and two db
+// endpoints, all of which can be deleted.
+func fakeDeleteAgent(t *testing.T) (*fakeAgent, *client.Client) {
+	agent, c := newFakeAgent(t)
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateReady, 1001, "k8s:app=db"),
+		testEndpoint(3, models.EndpointStateReady, 1001, "k8s:app=db"),
+	}
+	agent.respond("GET /endpoint", http.StatusOK, eps)
+	for _, ep := range eps {
+		id := strconv.FormatInt(ep.ID, 10)
+		agent.respond("GET /endpoint/"+id, http.StatusOK, ep)
+		agent.respond("DELETE /endpoint/"+id, http.StatusOK, nil)
+	}
+	return agent, c
+}
+
+// deleteCalls returns the number of times each endpoint was deleted.
+func deleteCalls(agent *fakeAgent) []int {
+	return []int{
+		agent.callCount("DELETE /endpoint/1"),
+		agent.callCount("DELETE /endpoint/2"),
+		agent.callCount("DELETE /endpoint/3"),
+	}
+}
+
+func TestEndpointDeleteEmptySelector(t *testing.T) {
+	for _, selector := range []string{",", "|", ",|,", " , "} {
+		agent, c := fakeDeleteAgent(t)
+
+		var out bytes.Buffer
+		err := runEndpointDelete(context.Background(), c, &out, []string{"-l", selector, "-yes"})
+		if err == nil {
+			t.Errorf("runEndpointDelete(-l %q) succeeded", selector)
+		}
+		if got := deleteCalls(agent); !reflect.DeepEqual(got, []int{0, 0, 0}) {
+			t.Errorf("runEndpointDelete(-l %q) deleted endpoints %v times", selector, got)
+		}
+	}
+}
+
+func TestEndpointDelete(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		calls []int
+		want  string
+	}{
+		{"id", []string{"-id", "2", "-yes"}, "", []int{0, 1, 0}, "Deleted 1 of 1 endpoints\n"},
+		{"selector", []string{"-l", "app=db", "-yes"}, "", []int{0, 1, 1}, "Deleted 2 of 2 endpoints\n"},
+		{"no match", []string{"-l", "app=cache", "-yes"}, "", []int{0, 0, 0}, "No endpoint matches app=cache\n"},
+		{"confirmed", []string{"-l", "app=web"}, "y\n", []int{1, 0, 0}, "Deleted 1 of 1 endpoints\n"},
+		{"declined", []string{"-l", "app=db"}, "n\n", []int{0, 0, 0}, "No endpoint deleted\n"},
+		{"no answer", []string{"-id", "1"}, "", []int{0, 0, 0}, "No endpoint deleted\n"},
+		{"interactive", []string{"-interactive"}, "2\ny\n", []int{0, 1, 0}, "Deleted 1 of 1 endpoints\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, tt.input)
+			agent, c := fakeDeleteAgent(t)
+
+			var out bytes.Buffer
+			if err := runEndpointDelete(context.Background(), c, &out, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := deleteCalls(agent); !reflect.DeepEqual(got, tt.calls) {
+				t.Errorf("endpoints deleted %v times, want %v", got, tt.calls)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runEndpointDelete() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointDeleteFailure(t *testing.T) {
+	agent, c := fakeDeleteAgent(t)
+	agent.respond("DELETE /endpoint/2", http.StatusNotFound, nil)
+
+	var out bytes.Buffer
+	err := runEndpointDelete(context.Background(), c, &out, []string{"-l", "app=db", "-yes"})
+	if want := "unable to delete 1 endpoints"; err == nil || err.Error() != want {
+		t.Errorf("runEndpointDelete() error = %v, want %s", err, want)
+	}
+	// The other endpoints are still deleted.
+	if got := deleteCalls(agent); !reflect.DeepEqual(got, []int{0, 1, 1}) {
+		t.Errorf("endpoints deleted %v times, want [0 1 1]", got)
+	}
+	if !strings.HasSuffix(out.String(), "Deleted 1 of 2 endpoints\n") {
+		t.Errorf("runEndpointDelete() = %q", out.String())
+	}
+}
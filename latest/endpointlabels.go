@@ -0,0 +1,179 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:  "endpoint-labels",
+		usage: "add or delete user labels of an endpoint (-id N [-add KEY=VALUE] [-delete KEY] [-dry-run])",
+		run:   runEndpointLabels,
+	})
+}
+
+// stringsFlag is a flag which may be given several times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func runEndpointLabels(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	var add, del stringsFlag
+	fs := flag.NewFlagSet("endpoint-labels", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if -id is not given")
+	fs.Var(&add, "add", "label to add, in the form [SOURCE:]KEY[=VALUE] (may be repeated)")
+	fs.Var(&del, "delete", "label to delete, in the form [SOURCE:]KEY (may be repeated)")
+	dryRun := fs.Bool("dry-run", false, "print the changes to the user labels without applying them")
+	fs.Parse(args)
+
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if *id == "" {
+		return errors.New("missing endpoint ID, use -id or -interactive")
+	}
+	toAdd, err := parseLabelArgs(add)
+	if err != nil {
+		return err
+	}
+	toDelete, err := parseLabelArgs(del)
+	if err != nil {
+		return err
+	}
+
+	status, err := getEndpointLabels(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	if len(toAdd) == 0 && len(toDelete) == 0 {
+		printEndpointLabels(out, status)
+		return nil
+	}
+
+	user := patchLabels(userLabels(status), toAdd, toDelete)
+	if *dryRun {
+		printLabelsPatch(out, userLabels(status), user)
+		return nil
+	}
+	params := endpoint.NewPatchEndpointIDLabelsParamsWithContext(ctx).
+		WithID(*id).
+		WithConfiguration(&models.LabelConfigurationSpec{User: user.GetModel()})
+	if _, err := c.Endpoint.PatchEndpointIDLabels(params); err != nil {
+		return err
+	}
+
+	status, err = getEndpointLabels(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	printEndpointLabels(out, status)
+	return nil
+}
+
+// parseLabelArgs parses labels given on the command line, in the form
+// [SOURCE:]KEY[=VALUE] or $KEY for reserved labels, e.g. $host. The labels
+// are keyed by their bare key.
+func parseLabelArgs(args []string) (labels.Labels, error) {
+	lbls := make(labels.Labels, len(args))
+	for _, arg := range args {
+		l := labels.ParseLabel(strings.TrimSpace(arg))
+		if !l.IsValid() {
+			return nil, fmt.Errorf("invalid label %q, must be of the form [SOURCE:]KEY[=VALUE]", arg)
+		}
+		lbls[l.Key] = l
+	}
+	lbls = canonicalizeLabels(lbls)
+	if err := validateLabels(lbls); err != nil {
+		return nil, err
+	}
+	warnUnknownSources(lbls)
+	return lbls, nil
+}
+
+// getEndpointLabels returns the label configuration of the endpoint with the
+// given ID.
+func getEndpointLabels(ctx context.Context, c *client.Client, id string) (*models.LabelConfigurationStatus, error) {
+	resp, err := c.Endpoint.GetEndpointIDLabels(endpoint.NewGetEndpointIDLabelsParamsWithContext(ctx).WithID(id))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Payload.Status == nil {
+		return &models.LabelConfigurationStatus{}, nil
+	}
+	return resp.Payload.Status, nil
+}
+
+// userLabels returns the labels set by the user on the endpoint.
+func userLabels(status *models.LabelConfigurationStatus) labels.Labels {
+	if status.Realized == nil {
+		return labels.Labels{}
+	}
+	return labels.NewLabelsFromModel(status.Realized.User)
+}
+
+// patchLabels returns a copy of lbls with the labels in add added and the
+// labels with the same keys as the ones in del removed.
+func patchLabels(lbls, add, del labels.Labels) labels.Labels {
+	return labelsRemove(labelsMerge(lbls, add), del)
+}
+
+// printLabelsPatch prints the labels added to before with a "+" and the labels
+// removed from it with a "-". A label whose value changes is printed as
+// removed and added.
+func printLabelsPatch(w io.Writer, before, after labels.Labels) {
+	removed := labelsDifference(before, after).GetPrintableModel()
+	added := labelsDifference(after, before).GetPrintableModel()
+	if len(removed) == 0 && len(added) == 0 {
+		fmt.Fprintln(w, "No label changed")
+		return
+	}
+	for _, l := range removed {
+		fmt.Fprintf(w, "- %s\n", l)
+	}
+	for _, l := range added {
+		fmt.Fprintf(w, "+ %s\n", l)
+	}
+}
+
+// printEndpointLabels prints the user labels of an endpoint, followed by the
+// labels its identity is derived from.
+func printEndpointLabels(w io.Writer, status *models.LabelConfigurationStatus) {
+	printLabelsSection(w, "User labels", userLabels(status))
+	printLabelsSection(w, "Security relevant labels", labels.NewLabelsFromModel(status.SecurityRelevant))
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func TestParseLabelArgs(t *testing.T) {
+	captureLogs(t)
+	host := labels.Label{Source: labels.LabelSourceReserved, Key: "host"}
+	tests := []struct {
+		name    string
+		args    []string
+		want    labels.Labels
+		wantErr bool
+	}{
+		{"shorthand", []string{"$host"}, labels.Labels{"host": host}, false},
+		{"shorthand with spaces", []string{" $host "}, labels.Labels{"host": host}, false},
+		{"source", []string{"reserved:host"}, labels.Labels{"host": host}, false},
+		{"mixed", []string{"$host", "K8S:app=web"}, labels.Labels{
+			"host": host,
+			"app":  {Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		}, false},
+		{"empty", []string{""}, nil, true},
+		{"semicolon", []string{"k8s:app=web;db"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabelArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabelArgs(%q) = %v, want error %t", tt.args, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLabelArgs(%q) = %#v, want %#v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchLabels(t *testing.T) {
+	user := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend"})
+	tests := []struct {
+		name     string
+		add, del []string
+		want     []string
+	}{
+		{"add", []string{"k8s:zone=a"}, nil, []string{"k8s:app=web", "k8s:tier=frontend", "k8s:zone=a"}},
+		{"replace value", []string{"k8s:app=db"}, nil, []string{"k8s:app=db", "k8s:tier=frontend"}},
+		// Labels are deleted by key, whatever their source or value.
+		{"delete", nil, []string{"container:tier"}, []string{"k8s:app=web"}},
+		{"delete wins", []string{"k8s:zone=a"}, []string{"zone"}, []string{"k8s:app=web", "k8s:tier=frontend"}},
+		{"delete unknown", nil, []string{"owner"}, []string{"k8s:app=web", "k8s:tier=frontend"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := patchLabels(user, labels.NewLabelsFromModel(tt.add), labels.NewLabelsFromModel(tt.del))
+			if !reflect.DeepEqual(got.GetPrintableModel(), tt.want) {
+				t.Errorf("patchLabels() = %v, want %v", got.GetPrintableModel(), tt.want)
+			}
+		})
+	}
+	if len(user) != 2 {
+		t.Errorf("patchLabels() modified its input: %v", user)
+	}
+}
+
+// fakeEndpointLabels serves the label configuration of endpoint 1 with the
+// given user labels, and records the labels patched in.
+func fakeEndpointLabels(t *testing.T, user ...string) (*fakeAgent, *client.Client, *[]string) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint/1/labels", http.StatusOK, &models.LabelConfiguration{
+		Status: &models.LabelConfigurationStatus{
+			Realized:         &models.LabelConfigurationSpec{User: user},
+			SecurityRelevant: append(models.Labels{"k8s:io.kubernetes.pod.namespace=default"}, user...),
+		},
+	})
+	var patched []string
+	agent.handle("PATCH /endpoint/1/labels", func(w http.ResponseWriter, r *http.Request) {
+		var spec models.LabelConfigurationSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			t.Error(err)
+		}
+		patched = labels.NewLabelsFromModel(spec.User).GetPrintableModel()
+	})
+	return agent, c, &patched
+}
+
+func TestEndpointLabelsPatch(t *testing.T) {
+	agent, c, patched := fakeEndpointLabels(t, "k8s:app=web", "k8s:tier=frontend")
+
+	var out bytes.Buffer
+	args := []string{"-id", "1", "-add", "k8s:zone=a", "-delete", "tier"}
+	if err := runEndpointLabels(context.Background(), c, &out, args); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"k8s:app=web", "k8s:zone=a"}; !reflect.DeepEqual(*patched, want) {
+		t.Errorf("patched user labels = %v, want %v", *patched, want)
+	}
+	if n := agent.callCount("GET /endpoint/1/labels"); n != 2 {
+		t.Errorf("labels were fetched %d times, want 2 to print the result", n)
+	}
+}
+
+func TestEndpointLabelsDryRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"changes", []string{"-add", "k8s:app=db", "-add", "k8s:zone=a", "-delete", "tier"},
+			"- k8s:app=web\n- k8s:tier=frontend\n+ k8s:app=db\n+ k8s:zone=a\n"},
+		{"no change", []string{"-add", "k8s:app=web", "-delete", "owner"},
+			"No label changed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, c, _ := fakeEndpointLabels(t, "k8s:app=web", "k8s:tier=frontend")
+
+			var out bytes.Buffer
+			args := append([]string{"-id", "1", "-dry-run"}, tt.args...)
+			if err := runEndpointLabels(context.Background(), c, &out, args); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runEndpointLabels() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+			if n := agent.callCount("PATCH /endpoint/1/labels"); n != 0 {
+				t.Errorf("labels were patched %d times with -dry-run", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "endpoint-log",
+		usage:    "print the status log of an endpoint (-id N | -interactive [-since 5m | -since-time RFC3339])",
+		run:      runEndpointLog,
+		readOnly: true,
+	})
+}
+
+func runEndpointLog(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoint-log", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if -id is not given")
+	since := fs.Duration("since", 0, "only print entries newer than this duration, e.g. 5m")
+	sinceTime := fs.String("since-time", "", "only print entries newer than this RFC3339 time")
+	fs.Parse(args)
+
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if *id == "" {
+		return errors.New("missing endpoint ID, use -id or -interactive")
+	}
+	cutoff, err := parseSince(*since, *sinceTime, time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Endpoint.GetEndpointIDLog(endpoint.NewGetEndpointIDLogParamsWithContext(ctx).WithID(*id))
+	if err != nil {
+		return err
+	}
+	printEndpointLog(out, filterEndpointLog(resp.Payload, cutoff))
+	return nil
+}
+
+// parseSince returns the time before which entries are filtered out, given
+// either a duration relative to now or an absolute RFC3339 time. It returns
+// the zero time if neither is set.
+func parseSince(since time.Duration, sinceTime string, now time.Time) (time.Time, error) {
+	switch {
+	case since != 0 && sinceTime != "":
+		return time.Time{}, errors.New("-since and -since-time cannot be combined")
+	case since < 0:
+		return time.Time{}, fmt.Errorf("invalid -since %s, must be positive", since)
+	case since > 0:
+		return now.Add(-since), nil
+	case sinceTime != "":
+		t, err := time.Parse(time.RFC3339, sinceTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid -since-time %q: %w", sinceTime, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+// filterEndpointLog returns the entries newer than cutoff. Entries
+// without a valid timestamp are left out, unless cutoff is the zero time
+// in which case all entries are returned.
+func filterEndpointLog(entries models.EndpointStatusLog, cutoff time.Time) models.EndpointStatusLog {
+	if cutoff.IsZero() {
+		return entries
+	}
+	var filtered models.EndpointStatusLog
+	for _, entry := range entries {
+		t, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil || t.Before(cutoff) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+func printEndpointLog(w io.Writer, entries models.EndpointStatusLog) {
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	printHeader(tw, "TIMESTAMP\tSTATUS\tSTATE\tMESSAGE")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Timestamp, entry.Code, entry.State, entry.Message)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		since     time.Duration
+		sinceTime string
+		want      time.Time
+		wantErr   bool
+	}{
+		{"neither", 0, "", time.Time{}, false},
+		{"relative", 90 * time.Minute, "", now.Add(-90 * time.Minute), false},
+		{"absolute", 0, "2021-06-01T10:00:00+02:00", time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC), false},
+		{"negative", -time.Minute, "", time.Time{}, true},
+		{"both", time.Minute, "2021-06-01T10:00:00Z", time.Time{}, true},
+		{"invalid time", 0, "yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSince(tt.since, tt.sinceTime, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince() error = %v, want error %t", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterEndpointLog(t *testing.T) {
+	old := &models.EndpointStatusChange{Timestamp: "2021-06-01T09:59:59.5Z", Message: "old"}
+	cutoffExact := &models.EndpointStatusChange{Timestamp: "2021-06-01T10:00:00Z", Message: "at cutoff"}
+	recent := &models.EndpointStatusChange{Timestamp: "2021-06-01T10:30:00.123456789Z", Message: "recent"}
+	invalid := &models.EndpointStatusChange{Timestamp: "", Message: "no timestamp"}
+	entries := models.EndpointStatusLog{recent, cutoffExact, invalid, old}
+
+	tests := []struct {
+		name   string
+		cutoff time.Time
+		want   models.EndpointStatusLog
+	}{
+		{"no cutoff", time.Time{}, entries},
+		{"cutoff", time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), models.EndpointStatusLog{recent, cutoffExact}},
+		{"everything older", time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterEndpointLog(entries, tt.cutoff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterEndpointLog() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
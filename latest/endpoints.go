@@ -0,0 +1,752 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+
+	"github.com/go-openapi/strfmt"
+)
+
+func init() {
+	register(&command{
+		name:     "endpoints",
+		usage:    "list the IP addresses of all local endpoints",
+		run:      runEndpoints,
+		readOnly: true,
+	})
+}
+
+// endpointsCSVHeader is the header row of the CSV output. The set and order of
+// the columns is part of the output format and must not change:
+//
+//	id         endpoint ID
+//	container  container name
+//	ipv4       IPv4 addresses, separated by spaces
+//	ipv6       IPv6 addresses, separated by spaces
+//	state      endpoint state
+//	labels     security relevant labels, separated by commas
+//
+// With -policy-status, a policy column holding the ingress and egress policy
+// enforcement is appended.
+var endpointsCSVHeader = []string{"id", "container", "ipv4", "ipv6", "state", "labels"}
+
+func runEndpoints(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoints", flag.ExitOnError)
+	output := fs.String("o", "text", "output format, one of: text, json, ndjson, csv, go-template=TEMPLATE, go-template-file=PATH; templates may call "+strings.Join(endpointsTemplateFuncNames(), ", "))
+	selector := fs.String("l", "", "only list endpoints matching all of the comma separated label selectors (key=value, key!=value, key, !key), or any of the groups of selectors separated by |")
+	state := fs.String("state", "", "only list endpoints in one of the comma separated states, e.g. ready,regenerating")
+	sortBy := fs.String("sort", "id", "sort endpoints by one of: id, name, ipv4, state")
+	reverse := fs.Bool("reverse", false, "reverse the sort order")
+	allAddresses := fs.Bool("all-addresses", false, "list all IPv4 and IPv6 addresses of the endpoints (text output only)")
+	groupByNamespace := fs.Bool("group-by-namespace", false, "group endpoints by their Kubernetes namespace (text output only)")
+	policyStatus := fs.Bool("policy-status", false, "add the ingress and egress policy enforcement to the output, as a column of the -fields table (id,name,ipv4 by default), a policy-status field of -o json and ndjson, or a policy column of -o csv")
+	count := fs.Bool("count", false, "only print the number of endpoints in total, per state and per namespace (text output only)")
+	fields := fs.String("fields", "", "print a table with the comma separated fields, one of: "+strings.Join(endpointFieldNames(), ", ")+" (text output only)")
+	maxWidth := fs.Int("max-width", -1, "truncate the labels and selector columns of -fields to this many characters, 0 for no limit (default the width of the terminal, no limit otherwise)")
+	compact := fs.Bool("compact", false, "write each endpoint of -o json without indentation")
+	orphans := fs.Bool("orphans", false, "only list endpoints without an identity, or with the init or unknown identity, and exit with code 1 if there are any")
+	fs.Parse(args)
+
+	write, err := endpointsWriter(*output, *allAddresses, *compact, *policyStatus)
+	if err != nil {
+		return err
+	}
+	states, err := parseEndpointStates(*state)
+	if err != nil {
+		return err
+	}
+	less, ok := endpointSortKeys[*sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q", *sortBy)
+	}
+	if *groupByNamespace {
+		if *output != "text" {
+			return fmt.Errorf("-group-by-namespace is not supported with output format %q", *output)
+		}
+		write = func(w io.Writer, eps []*models.Endpoint) error {
+			return writeEndpointsByNamespace(w, eps, *allAddresses)
+		}
+	}
+
+	if *policyStatus && *fields == "" && *output == "text" {
+		*fields = "id,name,ipv4"
+	}
+	if *fields != "" {
+		if *output != "text" {
+			return fmt.Errorf("-fields is not supported with output format %q", *output)
+		}
+		if *groupByNamespace {
+			return fmt.Errorf("-fields cannot be combined with -group-by-namespace")
+		}
+		if *maxWidth < 0 {
+			*maxWidth = terminalWidth()
+		}
+		names, err := parseEndpointFields(*fields)
+		if err != nil {
+			return err
+		}
+		if *policyStatus {
+			names = withEndpointField(names, "policy")
+		}
+		write = func(w io.Writer, eps []*models.Endpoint) error {
+			return printEndpointFields(w, eps, names, *maxWidth)
+		}
+	}
+
+	if *count {
+		if *output != "text" {
+			return fmt.Errorf("-count is not supported with output format %q", *output)
+		}
+		write = writeEndpointsCount
+	}
+
+	// List all endpoints
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	eps := filterEndpointsByLabels(resp.Payload, parseLabelExpr(*selector))
+	eps, filteredOut := filterEndpointsByState(eps, states)
+	if *orphans {
+		eps = filterOrphanEndpoints(eps)
+	}
+
+	sortEndpoints(eps, less, *reverse)
+
+	if err := write(out, eps); err != nil {
+		return err
+	}
+	if *output == "text" && filteredOut > 0 {
+		fmt.Fprintf(out, "%d endpoints not in state %s\n", filteredOut, *state)
+	}
+	if *orphans && len(eps) > 0 {
+		return errUnhealthy
+	}
+	return nil
+}
+
+// filterOrphanEndpoints returns the endpoints which have no identity yet, or
+// whose identity is labels.IDNameInit or labels.IDNameUnknown. Endpoints
+// stuck in that state usually point at a labeling problem.
+func filterOrphanEndpoints(eps []*models.Endpoint) []*models.Endpoint {
+	var orphans []*models.Endpoint
+	for _, ep := range eps {
+		if ep.Status == nil || ep.Status.Identity == nil {
+			orphans = append(orphans, ep)
+			continue
+		}
+		switch reservedIdentityName(labels.NewLabelsFromModel(ep.Status.Identity.Labels)) {
+		case labels.IDNameInit, labels.IDNameUnknown:
+			orphans = append(orphans, ep)
+		}
+	}
+	return orphans
+}
+
+// endpointSortKeys are the orderings endpoints can be sorted by.
+var endpointSortKeys = map[string]func(a, b *models.Endpoint) bool{
+	"id": func(a, b *models.Endpoint) bool {
+		return a.ID < b.ID
+	},
+	"name": func(a, b *models.Endpoint) bool {
+		return endpointName(a) < endpointName(b)
+	},
+	"ipv4": func(a, b *models.Endpoint) bool {
+		return bytes.Compare(endpointFirstIPv4(a), endpointFirstIPv4(b)) < 0
+	},
+	"state": func(a, b *models.Endpoint) bool {
+		return endpointState(a) < endpointState(b)
+	},
+}
+
+// endpointFields extract the fields which can be selected with -fields.
+var endpointFields = map[string]func(ep *models.Endpoint) string{
+	"id": func(ep *models.Endpoint) string {
+		return strconv.FormatInt(ep.ID, 10)
+	},
+	"name":      endpointName,
+	"container": endpointContainerName,
+	"namespace": endpointNamespace,
+	"ipv4": func(ep *models.Endpoint) string {
+		v4s, _ := endpointAddresses(ep)
+		return strings.Join(v4s, ",")
+	},
+	"ipv6": func(ep *models.Endpoint) string {
+		_, v6s := endpointAddresses(ep)
+		return strings.Join(v6s, ",")
+	},
+	"state": endpointState,
+	"labels": func(ep *models.Endpoint) string {
+		return labelsStringWithSep(endpointLabels(ep), ",")
+	},
+	"selector": func(ep *models.Endpoint) string {
+		return labelsToSelectorString(endpointLabels(ep))
+	},
+	"policy": endpointPolicyStatus,
+}
+
+// endpointPolicyStatus returns whether ingress and egress policies are
+// enforced on ep, e.g. "ingress:enabled egress:audit".
+func endpointPolicyStatus(ep *models.Endpoint) string {
+	if ep.Status == nil || ep.Status.Policy == nil || ep.Status.Policy.Realized == nil {
+		return "ingress:unknown egress:unknown"
+	}
+	ingress, egress := "disabled", "disabled"
+	switch ep.Status.Policy.Realized.PolicyEnabled {
+	case models.EndpointPolicyEnabledIngress:
+		ingress = "enabled"
+	case models.EndpointPolicyEnabledEgress:
+		egress = "enabled"
+	case models.EndpointPolicyEnabledBoth:
+		ingress, egress = "enabled", "enabled"
+	case models.EndpointPolicyEnabledAuditIngress:
+		ingress = "audit"
+	case models.EndpointPolicyEnabledAuditEgress:
+		egress = "audit"
+	case models.EndpointPolicyEnabledAuditBoth:
+		ingress, egress = "audit", "audit"
+	}
+	return "ingress:" + ingress + " egress:" + egress
+}
+
+func endpointFieldNames() []string {
+	names := make([]string, 0, len(endpointFields))
+	for name := range endpointFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseEndpointFields parses a comma separated list of field names.
+func parseEndpointFields(str string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(str, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := endpointFields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q, must be one of: %s", name, strings.Join(endpointFieldNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// withEndpointField returns fields with name added at the end, unless it is
+// already one of them.
+func withEndpointField(fields []string, name string) []string {
+	for _, f := range fields {
+		if f == name {
+			return fields
+		}
+	}
+	return append(fields, name)
+}
+
+// printEndpointFields prints a table with a column for each of the fields.
+// The labels and selector columns are truncated to maxWidth characters,
+// unless it is 0.
+func printEndpointFields(out io.Writer, eps []*models.Endpoint, fields []string, maxWidth int) error {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, strings.ToUpper(strings.Join(fields, "\t")))
+	values := make([]string, len(fields))
+	for _, ep := range eps {
+		for i, f := range fields {
+			values[i] = endpointFields[f](ep)
+			if maxWidth > 0 && (f == "labels" || f == "selector") {
+				values[i] = truncateList(strings.Split(values[i], ","), ",", maxWidth)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+// sortEndpoints sorts eps according to less, keeping the order of equal
+// endpoints.
+func sortEndpoints(eps []*models.Endpoint, less func(a, b *models.Endpoint) bool, reverse bool) {
+	sort.SliceStable(eps, func(i, j int) bool {
+		if reverse {
+			return less(eps[j], eps[i])
+		}
+		return less(eps[i], eps[j])
+	})
+}
+
+// endpointFirstIPv4 returns the first IPv4 address of ep in its 4-byte
+// representation, or nil if it has none.
+func endpointFirstIPv4(ep *models.Endpoint) net.IP {
+	v4s, _ := endpointAddresses(ep)
+	if len(v4s) == 0 {
+		return nil
+	}
+	return net.ParseIP(v4s[0]).To4()
+}
+
+// parseEndpointStates parses a comma separated list of endpoint states. All
+// states must be known to the API.
+func parseEndpointStates(str string) (map[models.EndpointState]struct{}, error) {
+	if str == "" {
+		return nil, nil
+	}
+	states := make(map[models.EndpointState]struct{})
+	for _, s := range strings.Split(str, ",") {
+		state := models.EndpointState(strings.TrimSpace(s))
+		if err := state.Validate(strfmt.Default); err != nil {
+			return nil, fmt.Errorf("invalid endpoint state %q: %w", state, err)
+		}
+		states[state] = struct{}{}
+	}
+	return states, nil
+}
+
+// filterEndpointsByState returns the endpoints in one of states, along with
+// the number of endpoints that were filtered out. All endpoints are returned
+// if states is empty.
+func filterEndpointsByState(eps []*models.Endpoint, states map[models.EndpointState]struct{}) ([]*models.Endpoint, int) {
+	if len(states) == 0 {
+		return eps, 0
+	}
+	var filtered []*models.Endpoint
+	for _, ep := range eps {
+		if _, ok := states[models.EndpointState(endpointState(ep))]; ok {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered, len(eps) - len(filtered)
+}
+
+// filterEndpointsByLabels returns the endpoints whose labels are selected
+// by expr.
+func filterEndpointsByLabels(eps []*models.Endpoint, expr labelExpr) []*models.Endpoint {
+	if len(expr) == 0 {
+		return eps
+	}
+	var filtered []*models.Endpoint
+	for _, ep := range eps {
+		if expr.matches(endpointLabels(ep)) {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+// endpointsWriter returns the function writing endpoints in the given output
+// format. allAddresses applies to the text format, compact to the JSON
+// format, and policyStatus to the JSON, NDJSON and CSV formats.
+func endpointsWriter(output string, allAddresses, compact, policyStatus bool) (func(io.Writer, []*models.Endpoint) error, error) {
+	format, arg := output, ""
+	if i := strings.Index(output, "="); i >= 0 {
+		format, arg = output[:i], output[i+1:]
+	}
+
+	switch format {
+	case "text":
+		return func(w io.Writer, eps []*models.Endpoint) error {
+			return printEndpointsStreaming(w, eps, allAddresses)
+		}, nil
+	case "json":
+		return func(w io.Writer, eps []*models.Endpoint) error {
+			return writeEndpointsJSON(w, eps, compact, policyStatus)
+		}, nil
+	case "ndjson":
+		return func(w io.Writer, eps []*models.Endpoint) error {
+			return writeEndpointsNDJSON(w, eps, policyStatus)
+		}, nil
+	case "csv":
+		return func(w io.Writer, eps []*models.Endpoint) error {
+			return writeEndpointsCSV(w, eps, policyStatus)
+		}, nil
+	}
+	if policyStatus {
+		return nil, fmt.Errorf("-policy-status is not supported with output format %q", output)
+	}
+	switch format {
+	case "go-template":
+		return endpointsTemplateWriter(arg)
+	case "go-template-file":
+		text, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return endpointsTemplateWriter(string(text))
+	}
+	return nil, fmt.Errorf("unknown output format %q", output)
+}
+
+// endpointView is the flattened view of an endpoint that templates are
+// executed against. The full model is available as .Endpoint.
+type endpointView struct {
+	ID            int64
+	ContainerName string
+	IPv4          []string
+	IPv6          []string
+	State         string
+	Labels        labels.Labels
+	Endpoint      *models.Endpoint
+}
+
+func newEndpointView(ep *models.Endpoint) endpointView {
+	v4s, v6s := endpointAddresses(ep)
+	return endpointView{
+		ID:            ep.ID,
+		ContainerName: endpointContainerName(ep),
+		IPv4:          v4s,
+		IPv6:          v6s,
+		State:         endpointState(ep),
+		Labels:        endpointLabels(ep),
+		Endpoint:      ep,
+	}
+}
+
+// endpointsTemplateFuncs are the functions available to endpoint templates in
+// addition to the builtin ones:
+//
+//	join    joins a list of strings with a separator, e.g. {{join .IPv4 " "}}
+//	labels  renders labels as a sorted, comma separated string
+//	short   renders a label as KEY=VALUE, without its source
+//	json    renders any value as compact JSON
+//	default returns its first argument if the second one is empty
+var endpointsTemplateFuncs = template.FuncMap{
+	"join": func(elems []string, sep string) string {
+		return strings.Join(elems, sep)
+	},
+	"labels": func(l labels.Labels) string {
+		return l.String()
+	},
+	"short": func(l labels.Label) string {
+		if l.Value == "" {
+			return l.Key
+		}
+		return l.Key + "=" + l.Value
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"default": func(def string, v interface{}) interface{} {
+		if v == nil || fmt.Sprint(v) == "" {
+			return def
+		}
+		return v
+	},
+}
+
+func endpointsTemplateFuncNames() []string {
+	names := make([]string, 0, len(endpointsTemplateFuncs))
+	for name := range endpointsTemplateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// endpointsTemplateWriter parses text as a Go template and returns a function
+// executing it against the views of all endpoints.
+func endpointsTemplateWriter(text string) (func(io.Writer, []*models.Endpoint) error, error) {
+	tmpl, err := template.New("endpoints").Funcs(endpointsTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template %q: %w", text, err)
+	}
+	return func(w io.Writer, eps []*models.Endpoint) error {
+		views := make([]endpointView, 0, len(eps))
+		for _, ep := range eps {
+			views = append(views, newEndpointView(ep))
+		}
+		if err := tmpl.Execute(w, views); err != nil {
+			return fmt.Errorf("unable to execute template %q: %w", text, err)
+		}
+		return nil
+	}, nil
+}
+
+// endpointAddresses returns the IPv4 and IPv6 addresses of ep, across all
+// addressing entries.
+func endpointAddresses(ep *models.Endpoint) (v4s, v6s []string) {
+	if ep.Status == nil || ep.Status.Networking == nil {
+		return nil, nil
+	}
+	for _, ip := range ep.Status.Networking.Addressing {
+		if ip.IPV4 != "" {
+			v4s = append(v4s, ip.IPV4)
+		}
+		if ip.IPV6 != "" {
+			v6s = append(v6s, ip.IPV6)
+		}
+	}
+	return v4s, v6s
+}
+
+// endpointContainerName returns the name of the container backing ep, if any.
+func endpointContainerName(ep *models.Endpoint) string {
+	if ep.Status == nil || ep.Status.ExternalIdentifiers == nil {
+		return ""
+	}
+	return ep.Status.ExternalIdentifiers.ContainerName
+}
+
+// endpointName returns the name of the pod backing ep, in the form
+// namespace/name, or the container name for endpoints not managed by
+// Kubernetes.
+func endpointName(ep *models.Endpoint) string {
+	if ep.Status == nil || ep.Status.ExternalIdentifiers == nil {
+		return ""
+	}
+	ids := ep.Status.ExternalIdentifiers
+	if ids.K8sPodName != "" {
+		return ids.K8sNamespace + "/" + ids.K8sPodName
+	}
+	return ids.ContainerName
+}
+
+// endpointState returns the state of ep.
+func endpointState(ep *models.Endpoint) string {
+	if ep.Status == nil {
+		return ""
+	}
+	return string(ep.Status.State)
+}
+
+// streamFlushInterval is the number of endpoints after which streamed output
+// is flushed to the underlying writer.
+const streamFlushInterval = 1000
+
+// printEndpointsStreaming prints the IPv4 addresses of the endpoints, or all
+// of their addresses if allAddresses is set. Rows are written incrementally
+// so that output starts before all endpoints have been formatted.
+func printEndpointsStreaming(w io.Writer, eps []*models.Endpoint, allAddresses bool) error {
+	bw := bufio.NewWriter(w)
+	for i, ep := range eps {
+		ips, v6s := endpointAddresses(ep)
+		if allAddresses {
+			ips = append(ips, v6s...)
+		}
+		if len(ips) > 0 {
+			fmt.Fprintf(bw, "EP ID %d has IP addresses: %s\n", ep.ID, strings.Join(ips, ", "))
+		} else {
+			fmt.Fprintf(bw, "EP ID %d does not have an IP address\n", ep.ID)
+		}
+		if (i+1)%streamFlushInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// writeEndpointsJSON writes the endpoints as a JSON array, indented unless
+// compact is set. Each endpoint is encoded on its own instead of marshaling
+// the whole list at once. The policy enforcement of the endpoints is added if
+// policyStatus is set.
+func writeEndpointsJSON(w io.Writer, eps []*models.Endpoint, compact, policyStatus bool) error {
+	bw := bufio.NewWriter(rawOutput(w))
+	if len(eps) == 0 {
+		bw.WriteString("[]\n")
+		return bw.Flush()
+	}
+
+	// The separators and indentation are the ones json.Encoder uses for a
+	// slice, so that the output is the same as writeJSON(w, eps, compact).
+	open, sep, end := "[", ",", "]\n"
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if !compact {
+		open, sep, end = "[\n  ", ",\n  ", "\n]\n"
+		enc.SetIndent("  ", "  ")
+	}
+	bw.WriteString(open)
+	for i, ep := range eps {
+		if i > 0 {
+			bw.WriteString(sep)
+		}
+		buf.Reset()
+		if err := enc.Encode(newContextEndpoint(ep, policyStatus)); err != nil {
+			return err
+		}
+		bw.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		if (i+1)%streamFlushInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	bw.WriteString(end)
+	return bw.Flush()
+}
+
+// contextEndpoint is an endpoint along with the name given with -context and
+// its policy enforcement with -policy-status, as written in JSON output.
+type contextEndpoint struct {
+	Context      string `json:"context,omitempty"`
+	PolicyStatus string `json:"policy-status,omitempty"`
+	*models.Endpoint
+}
+
+func newContextEndpoint(ep *models.Endpoint, policyStatus bool) contextEndpoint {
+	v := contextEndpoint{Context: *contextName, Endpoint: ep}
+	if policyStatus {
+		v.PolicyStatus = endpointPolicyStatus(ep)
+	}
+	return v
+}
+
+// writeEndpointsNDJSON writes each endpoint as compact JSON on its own line,
+// without an enclosing array. The policy enforcement of the endpoints is
+// added if policyStatus is set.
+func writeEndpointsNDJSON(w io.Writer, eps []*models.Endpoint, policyStatus bool) error {
+	bw := bufio.NewWriter(rawOutput(w))
+	enc := json.NewEncoder(bw)
+	for i, ep := range eps {
+		if err := enc.Encode(newContextEndpoint(ep, policyStatus)); err != nil {
+			return err
+		}
+		if (i+1)%streamFlushInterval == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// podNamespaceLabel is the key of the label holding the Kubernetes namespace
+// of an endpoint's pod.
+const podNamespaceLabel = "io.kubernetes.pod.namespace"
+
+// noNamespace is the group of endpoints without a Kubernetes namespace.
+const noNamespace = "<none>"
+
+// endpointNamespace returns the Kubernetes namespace of ep as found in its
+// labels, or noNamespace.
+func endpointNamespace(ep *models.Endpoint) string {
+	l, ok := endpointLabels(ep)[podNamespaceLabel]
+	if !ok || l.Source != labels.LabelSourceK8s {
+		return noNamespace
+	}
+	return l.Value
+}
+
+// groupEndpoints groups eps by the key returned by keyFn. The keys are
+// returned in alphabetical order.
+func groupEndpoints(eps []*models.Endpoint, keyFn func(*models.Endpoint) string) (map[string][]*models.Endpoint, []string) {
+	groups := make(map[string][]*models.Endpoint)
+	for _, ep := range eps {
+		key := keyFn(ep)
+		groups[key] = append(groups[key], ep)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return groups, keys
+}
+
+// writeEndpointsByNamespace prints the endpoints grouped by namespace, with
+// the namespaces in alphabetical order.
+func writeEndpointsByNamespace(w io.Writer, eps []*models.Endpoint, allAddresses bool) error {
+	groups, namespaces := groupEndpoints(eps, endpointNamespace)
+	for i, ns := range namespaces {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Namespace %s (%d endpoints):\n", ns, len(groups[ns]))
+		if err := printEndpointsStreaming(w, groups[ns], allAddresses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEndpointsCount prints the number of endpoints, in total, per state and
+// per namespace, along with the number of endpoints without an identity.
+func writeEndpointsCount(out io.Writer, eps []*models.Endpoint) error {
+	noIdentity := 0
+	for _, ep := range eps {
+		if ep.Status == nil || ep.Status.Identity == nil {
+			noIdentity++
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "Endpoints:\t%d\n", len(eps))
+	fmt.Fprintf(w, "Without identity:\t%d\n", noIdentity)
+	for _, g := range []struct {
+		name  string
+		keyFn func(*models.Endpoint) string
+	}{
+		{"State", endpointState},
+		{"Namespace", endpointNamespace},
+	} {
+		groups, keys := groupEndpoints(eps, g.keyFn)
+		fmt.Fprintf(w, "\n%s:\n", g.name)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s\t%d\n", key, len(groups[key]))
+		}
+	}
+	return w.Flush()
+}
+
+// writeEndpointsCSV writes the endpoints as CSV with the columns described by
+// endpointsCSVHeader, followed by a policy column if policyStatus is set.
+func writeEndpointsCSV(w io.Writer, eps []*models.Endpoint, policyStatus bool) error {
+	cw := csv.NewWriter(rawOutput(w))
+	if !*noHeaders {
+		header := endpointsCSVHeader
+		if policyStatus {
+			header = append(header[:len(header):len(header)], "policy")
+		}
+		cw.Write(header)
+	}
+	for _, ep := range eps {
+		v4s, v6s := endpointAddresses(ep)
+		row := []string{
+			strconv.FormatInt(ep.ID, 10),
+			endpointContainerName(ep),
+			strings.Join(v4s, " "),
+			strings.Join(v6s, " "),
+			endpointState(ep),
+			endpointLabels(ep).String(),
+		}
+		if policyStatus {
+			row = append(row, endpointPolicyStatus(ep))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,599 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestWriteEndpointsJSONCompact(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateNotReady, 0),
+	}
+	var pretty, compact bytes.Buffer
+	if err := writeEndpointsJSON(&pretty, eps, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeEndpointsJSON(&compact, eps, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if compact.Len() >= pretty.Len() {
+		t.Errorf("compact output is %d bytes, not smaller than the %d bytes of the indented one", compact.Len(), pretty.Len())
+	}
+	if !strings.Contains(pretty.String(), "\n  ") {
+		t.Errorf("output not indented:\n%s", pretty.String())
+	}
+
+	var fromPretty, fromCompact []interface{}
+	if err := json.Unmarshal(pretty.Bytes(), &fromPretty); err != nil {
+		t.Fatalf("invalid indented JSON: %v\n%s", err, pretty.String())
+	}
+	if err := json.Unmarshal(compact.Bytes(), &fromCompact); err != nil {
+		t.Fatalf("invalid compact JSON: %v\n%s", err, compact.String())
+	}
+	if !reflect.DeepEqual(fromPretty, fromCompact) {
+		t.Errorf("indented and compact outputs differ:\n%s\n%s", pretty.String(), compact.String())
+	}
+}
+
+func TestWriteEndpointsJSON(t *testing.T) {
+	defer func(old string) { *contextName = old }(*contextName)
+	*contextName = "kind-1"
+
+	all := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateNotReady, 0),
+		withAddress(testEndpoint(3, models.EndpointStateReady, 1001, "k8s:app=<db>"), "10.0.0.3", ""),
+	}
+	for _, n := range []int{1, 3} {
+		for _, compact := range []bool{false, true} {
+			eps := all[:n]
+			views := make([]contextEndpoint, len(eps))
+			for i, ep := range eps {
+				views[i] = newContextEndpoint(ep, true)
+			}
+			var got, want bytes.Buffer
+			if err := writeEndpointsJSON(&got, eps, compact, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeJSON(&want, views, compact); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("writeEndpointsJSON() of %d endpoints, compact %t =\n%s\nwant\n%s", n, compact, got.String(), want.String())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeEndpointsJSON(&buf, nil, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("writeEndpointsJSON() without endpoints = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestWithEndpointField(t *testing.T) {
+	tests := []struct {
+		fields string
+		want   []string
+	}{
+		{"id,name,ipv4", []string{"id", "name", "ipv4", "policy"}},
+		{"id,policy,name", []string{"id", "policy", "name"}},
+		{"id, policy", []string{"id", "policy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			names, err := parseEndpointFields(tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := withEndpointField(names, "policy"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withEndpointField() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointPolicyStatus(t *testing.T) {
+	tests := []struct {
+		enabled models.EndpointPolicyEnabled
+		want    string
+	}{
+		{models.EndpointPolicyEnabledNone, "ingress:disabled egress:disabled"},
+		{models.EndpointPolicyEnabledIngress, "ingress:enabled egress:disabled"},
+		{models.EndpointPolicyEnabledEgress, "ingress:disabled egress:enabled"},
+		{models.EndpointPolicyEnabledBoth, "ingress:enabled egress:enabled"},
+		{models.EndpointPolicyEnabledAuditIngress, "ingress:audit egress:disabled"},
+		{models.EndpointPolicyEnabledAuditEgress, "ingress:disabled egress:audit"},
+		{models.EndpointPolicyEnabledAuditBoth, "ingress:audit egress:audit"},
+	}
+	for _, tt := range tests {
+		ep := testEndpoint(1, models.EndpointStateReady, 1000)
+		ep.Status.Policy = &models.EndpointPolicyStatus{
+			Realized: &models.EndpointPolicy{PolicyEnabled: tt.enabled},
+		}
+		if got := endpointPolicyStatus(ep); got != tt.want {
+			t.Errorf("endpointPolicyStatus(%s) = %q, want %q", tt.enabled, got, tt.want)
+		}
+	}
+
+	if got, want := endpointPolicyStatus(testEndpoint(1, models.EndpointStateReady, 1000)), "ingress:unknown egress:unknown"; got != want {
+		t.Errorf("endpointPolicyStatus() without realized policy = %q, want %q", got, want)
+	}
+}
+
+func TestEndpointsPolicyStatusOutput(t *testing.T) {
+	ep := testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web")
+	ep.Status.Policy = &models.EndpointPolicyStatus{
+		Realized: &models.EndpointPolicy{PolicyEnabled: models.EndpointPolicyEnabledAuditIngress},
+	}
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{ep})
+
+	tests := []struct {
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"text", "ingress:audit egress:disabled", false},
+		{"json", `"policy-status": "ingress:audit egress:disabled"`, false},
+		{"ndjson", `"policy-status":"ingress:audit egress:disabled"`, false},
+		{"csv", ",policy\n1,,,,ready,k8s:app=web,ingress:audit egress:disabled\n", false},
+		{"go-template={{.ID}}", "", true},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := runEndpoints(context.Background(), c, &out, []string{"-policy-status", "-o", tt.output})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("endpoints -policy-status -o %s succeeded", tt.output)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("endpoints -policy-status -o %s: %v", tt.output, err)
+			continue
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("endpoints -policy-status -o %s =\n%s\nwant it to contain %q", tt.output, out.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteEndpointsCSV(t *testing.T) {
+	web := withAddress(testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:tier=frontend"), "10.0.0.1", "fd00::1")
+	withAddress(web, "10.0.0.2", "")
+	web.Status.ExternalIdentifiers = &models.EndpointIdentifiers{ContainerName: "web"}
+	eps := []*models.Endpoint{
+		web,
+		testEndpoint(2, models.EndpointStateWaitingForIdentity, 0),
+	}
+
+	tests := []struct {
+		name         string
+		noHeaders    bool
+		policyStatus bool
+		want         string
+	}{
+		{"headers", false, false, "" +
+			"id,container,ipv4,ipv6,state,labels\n" +
+			"1,web,10.0.0.1 10.0.0.2,fd00::1,ready,\"k8s:app=web,k8s:tier=frontend\"\n" +
+			"2,,,,waiting-for-identity,\n"},
+		{"no headers", true, false, "" +
+			"1,web,10.0.0.1 10.0.0.2,fd00::1,ready,\"k8s:app=web,k8s:tier=frontend\"\n" +
+			"2,,,,waiting-for-identity,\n"},
+		{"policy status", false, true, "" +
+			"id,container,ipv4,ipv6,state,labels,policy\n" +
+			"1,web,10.0.0.1 10.0.0.2,fd00::1,ready,\"k8s:app=web,k8s:tier=frontend\",ingress:unknown egress:unknown\n" +
+			"2,,,,waiting-for-identity,,ingress:unknown egress:unknown\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(old bool) { *noHeaders = old }(*noHeaders)
+			*noHeaders = tt.noHeaders
+
+			var buf bytes.Buffer
+			if err := writeEndpointsCSV(&buf, eps, tt.policyStatus); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeEndpointsCSV() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointsTemplateWriter(t *testing.T) {
+	eps := []*models.Endpoint{
+		withAddress(testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"), "10.0.0.1", "fd00::1"),
+		testEndpoint(2, models.EndpointStateNotReady, 0),
+	}
+	write, err := endpointsTemplateWriter("{{range .}}{{.ID}} {{.State}} {{.IPv4}} {{.Endpoint.Status.State}}\n{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, eps); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "1 ready [10.0.0.1] ready\n2 not-ready [] not-ready\n"; got != want {
+		t.Errorf("template output = %q, want %q", got, want)
+	}
+
+	if _, err := endpointsTemplateWriter("{{range .}}"); err == nil {
+		t.Error("endpointsTemplateWriter() accepted an unterminated range")
+	}
+	write, err = endpointsTemplateWriter("{{range .}}{{.Missing}}{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := write(&bytes.Buffer{}, eps); err == nil {
+		t.Error("template referring to an unknown field executed")
+	}
+}
+
+func TestWriteEndpointsByNamespace(t *testing.T) {
+	eps := []*models.Endpoint{
+		withAddress(testEndpoint(1, models.EndpointStateReady, 0, "k8s:io.kubernetes.pod.namespace=kube-system"), "10.0.0.1", ""),
+		withAddress(testEndpoint(2, models.EndpointStateReady, 0, "k8s:io.kubernetes.pod.namespace=default"), "10.0.0.2", "fd00::2"),
+		testEndpoint(3, models.EndpointStateReady, 0, "container:io.kubernetes.pod.namespace=default"),
+		withAddress(testEndpoint(4, models.EndpointStateReady, 0, "k8s:io.kubernetes.pod.namespace=default"), "10.0.0.4", ""),
+	}
+	var buf bytes.Buffer
+	if err := writeEndpointsByNamespace(&buf, eps, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `Namespace <none> (1 endpoints):
+EP ID 3 does not have an IP address
+
+Namespace default (2 endpoints):
+EP ID 2 has IP addresses: 10.0.0.2
+EP ID 4 has IP addresses: 10.0.0.4
+
+Namespace kube-system (1 endpoints):
+EP ID 1 has IP addresses: 10.0.0.1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeEndpointsByNamespace() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// endpointIDs returns the IDs of eps, in order.
+func endpointIDs(eps []*models.Endpoint) []int64 {
+	var ids []int64
+	for _, ep := range eps {
+		ids = append(ids, ep.ID)
+	}
+	return ids
+}
+
+func TestFilterEndpointsByState(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 0),
+		testEndpoint(2, models.EndpointStateNotReady, 0),
+		testEndpoint(3, models.EndpointStateRegenerating, 0),
+		testEndpoint(4, models.EndpointStateReady, 0),
+	}
+	tests := []struct {
+		states   string
+		want     []int64
+		filtered int
+		wantErr  bool
+	}{
+		{"", []int64{1, 2, 3, 4}, 0, false},
+		{"ready", []int64{1, 4}, 2, false},
+		{"not-ready, regenerating", []int64{2, 3}, 2, false},
+		{"disconnected", nil, 4, false},
+		{"ready,started", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.states, func(t *testing.T) {
+			states, err := parseEndpointStates(tt.states)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEndpointStates() = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, filtered := filterEndpointsByState(eps, states)
+			if ids := endpointIDs(got); !reflect.DeepEqual(ids, tt.want) || filtered != tt.filtered {
+				t.Errorf("filterEndpointsByState() = %v, %d, want %v, %d", ids, filtered, tt.want, tt.filtered)
+			}
+		})
+	}
+}
+
+func TestSortEndpoints(t *testing.T) {
+	pod := func(ep *models.Endpoint, ns, name string) *models.Endpoint {
+		ep.Status.ExternalIdentifiers = &models.EndpointIdentifiers{K8sNamespace: ns, K8sPodName: name}
+		return ep
+	}
+	newEndpoints := func() []*models.Endpoint {
+		return []*models.Endpoint{
+			pod(withAddress(testEndpoint(3, models.EndpointStateReady, 0), "10.0.0.10", ""), "default", "web"),
+			pod(withAddress(testEndpoint(1, models.EndpointStateNotReady, 0), "10.0.0.9", ""), "default", "db"),
+			pod(testEndpoint(2, models.EndpointStateReady, 0), "kube-system", "dns"),
+		}
+	}
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []int64
+	}{
+		{"id", false, []int64{1, 2, 3}},
+		{"id", true, []int64{3, 2, 1}},
+		{"name", false, []int64{1, 3, 2}},
+		// Addresses are compared numerically, endpoints without one first.
+		{"ipv4", false, []int64{2, 1, 3}},
+		// Equal endpoints keep their order.
+		{"state", false, []int64{1, 3, 2}},
+	}
+	for _, tt := range tests {
+		eps := newEndpoints()
+		sortEndpoints(eps, endpointSortKeys[tt.key], tt.reverse)
+		if got := endpointIDs(eps); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort by %s (reverse %t) = %v, want %v", tt.key, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestPrintEndpointsStreaming(t *testing.T) {
+	eps := []*models.Endpoint{
+		withAddress(withAddress(testEndpoint(1, models.EndpointStateReady, 0), "10.0.0.1", "fd00::1"), "10.0.0.2", ""),
+		withAddress(testEndpoint(2, models.EndpointStateReady, 0), "", "fd00::2"),
+		testEndpoint(3, models.EndpointStateNotReady, 0),
+	}
+	tests := []struct {
+		allAddresses bool
+		want         string
+	}{
+		{false, "" +
+			"EP ID 1 has IP addresses: 10.0.0.1, 10.0.0.2\n" +
+			"EP ID 2 does not have an IP address\n" +
+			"EP ID 3 does not have an IP address\n"},
+		{true, "" +
+			"EP ID 1 has IP addresses: 10.0.0.1, 10.0.0.2, fd00::1\n" +
+			"EP ID 2 has IP addresses: fd00::2\n" +
+			"EP ID 3 does not have an IP address\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := printEndpointsStreaming(&buf, eps, tt.allAddresses); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("printEndpointsStreaming(allAddresses %t) =\n%s\nwant\n%s", tt.allAddresses, got, tt.want)
+		}
+	}
+}
+
+func TestPrintEndpointFields(t *testing.T) {
+	web := withAddress(testEndpoint(12, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:io.kubernetes.pod.namespace=default"), "10.0.0.1", "fd00::1")
+	web.Status.ExternalIdentifiers = &models.EndpointIdentifiers{K8sNamespace: "default", K8sPodName: "web-0"}
+	eps := []*models.Endpoint{web, testEndpoint(3, models.EndpointStateNotReady, 0)}
+
+	if _, err := parseEndpointFields("id,pod"); err == nil {
+		t.Error("parseEndpointFields() accepted an unknown field")
+	}
+	fields, err := parseEndpointFields("id, name,namespace,ipv4,state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printEndpointFields(&buf, eps, fields, 0); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"ID   NAME            NAMESPACE   IPV4       STATE\n" +
+		"12   default/web-0   default     10.0.0.1   ready\n" +
+		"3                    <none>                 not-ready\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printEndpointFields() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteEndpointsCount(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:io.kubernetes.pod.namespace=default"),
+		testEndpoint(2, models.EndpointStateReady, 1000, "k8s:io.kubernetes.pod.namespace=default"),
+		testEndpoint(3, models.EndpointStateWaitingForIdentity, 0, "k8s:io.kubernetes.pod.namespace=kube-system"),
+		testEndpoint(4, models.EndpointStateReady, 1, "reserved:host"),
+	}
+	var buf bytes.Buffer
+	if err := writeEndpointsCount(&buf, eps); err != nil {
+		t.Fatal(err)
+	}
+	// Each section is aligned on its own.
+	want := `Endpoints:          4
+Without identity:   1
+
+State:
+  ready                  3
+  waiting-for-identity   1
+
+Namespace:
+  <none>        1
+  default       2
+  kube-system   1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeEndpointsCount() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteEndpointsNDJSON(t *testing.T) {
+	defer func(old string) { *contextName = old }(*contextName)
+	*contextName = "kind-1"
+
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateNotReady, 0),
+	}
+	var buf bytes.Buffer
+	if err := writeEndpointsNDJSON(newPrefixWriter(nopCloser{&buf}, *contextName), eps, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(eps) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(eps), buf.String())
+	}
+	for i, line := range lines {
+		var got struct {
+			Context string `json:"context"`
+			ID      int64  `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d is not JSON: %v\n%s", i, err, line)
+		}
+		if got.Context != "kind-1" || got.ID != eps[i].ID {
+			t.Errorf("line %d = %+v, want endpoint %d in context kind-1", i, got, eps[i].ID)
+		}
+	}
+}
+
+func TestEndpointsTemplateFuncs(t *testing.T) {
+	eps := []*models.Endpoint{
+		withAddress(withAddress(testEndpoint(1, models.EndpointStateReady, 1000, "k8s:tier=frontend", "k8s:app=web"), "10.0.0.1", ""), "10.0.0.2", ""),
+		testEndpoint(2, models.EndpointStateNotReady, 0, "reserved:init"),
+	}
+	tests := []struct {
+		name, text, want string
+	}{
+		{"join", `{{range .}}{{join .IPv4 " "}};{{end}}`, "10.0.0.1 10.0.0.2;;"},
+		{"labels", `{{range .}}{{labels .Labels}};{{end}}`, "k8s:app=web,k8s:tier=frontend;reserved:init;"},
+		{"short", `{{range .}}{{range .Labels}}{{short .}} {{end}};{{end}}`, "app=web tier=frontend ;init ;"},
+		{"json", `{{range .}}{{json .IPv4}};{{end}}`, `["10.0.0.1","10.0.0.2"];null;`},
+		{"default", `{{range .}}{{default "-" .ContainerName}};{{end}}`, "-;-;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write, err := endpointsTemplateWriter(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := write(&buf, eps); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("template %s = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterOrphanEndpoints(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateWaitingForIdentity, 0),
+		testEndpoint(3, models.EndpointStateReady, 5, "reserved:init"),
+		testEndpoint(4, models.EndpointStateReady, 1, "reserved:host"),
+		{ID: 5},
+	}
+	if got, want := endpointIDs(filterOrphanEndpoints(eps)), []int64{2, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filterOrphanEndpoints() = %v, want %v", got, want)
+	}
+}
+
+// benchmarkEndpoints returns n endpoints with an address and a few labels, as
+// found on a dense node.
+func benchmarkEndpoints(n int) []*models.Endpoint {
+	eps := make([]*models.Endpoint, n)
+	for i := range eps {
+		id := int64(i + 1)
+		ep := testEndpoint(id, models.EndpointStateReady, 1000+id%100,
+			"k8s:app=app-"+strconv.FormatInt(id%100, 10),
+			"k8s:io.kubernetes.pod.namespace=default")
+		eps[i] = withAddress(ep, fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff), "")
+	}
+	return eps
+}
+
+// printEndpointsBuffered formats all endpoints into memory before writing
+// them to w at once, as done before printEndpointsStreaming. It is the
+// baseline of BenchmarkPrintEndpointsStreaming.
+func printEndpointsBuffered(w io.Writer, eps []*models.Endpoint) error {
+	var sb strings.Builder
+	for _, ep := range eps {
+		v4s, _ := endpointAddresses(ep)
+		if len(v4s) > 0 {
+			fmt.Fprintf(&sb, "EP ID %d has IP addresses: %s\n", ep.ID, strings.Join(v4s, ", "))
+		} else {
+			fmt.Fprintf(&sb, "EP ID %d does not have an IP address\n", ep.ID)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func BenchmarkPrintEndpointsBuffered(b *testing.B) {
+	eps := benchmarkEndpoints(10000)
+	b.Run("text", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := printEndpointsBuffered(io.Discard, eps); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		views := make([]contextEndpoint, len(eps))
+		for i, ep := range eps {
+			views[i] = newContextEndpoint(ep, false)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := writeJSON(io.Discard, views, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPrintEndpointsStreaming(b *testing.B) {
+	eps := benchmarkEndpoints(10000)
+	b.Run("text", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := printEndpointsStreaming(io.Discard, eps, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := writeEndpointsJSON(io.Discard, eps, false, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
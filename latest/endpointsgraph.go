@@ -0,0 +1,153 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "endpoints-graph",
+		usage:    "print the endpoints clustered by identity as a Graphviz DOT graph (use -out graph.dot to save it)",
+		run:      runEndpointsGraph,
+		readOnly: true,
+	})
+}
+
+// graphEntities are the reserved identities drawn as nodes of their own,
+// with an edge to or from each endpoint whose realized policy allows traffic
+// with them.
+var graphEntities = []struct {
+	id   int64
+	name string
+}{
+	{1, labels.IDNameHost},
+	{2, labels.IDNameWorld},
+}
+
+func runEndpointsGraph(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("endpoints-graph", flag.ExitOnError)
+	fs.Parse(args)
+
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return writeEndpointsGraph(out, resp.Payload)
+}
+
+// writeEndpointsGraph writes a DOT graph with a cluster per identity holding
+// its endpoints. Endpoints without an identity are left out of the clusters.
+// If the realized policy of an endpoint is known, edges show whether it may
+// send traffic to, or receive traffic from, the host and the world.
+func writeEndpointsGraph(w io.Writer, eps []*models.Endpoint) error {
+	fmt.Fprintln(w, "digraph endpoints {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+
+	clusters := make(map[int64][]*models.Endpoint)
+	var ids []int64
+	for _, ep := range eps {
+		id := identityID(endpointIdentity(ep))
+		if _, ok := clusters[id]; !ok {
+			ids = append(ids, id)
+		}
+		clusters[id] = append(clusters[id], ep)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		members := clusters[id]
+		sortEndpoints(members, endpointSortKeys["id"], false)
+		indent := "  "
+		if id != 0 {
+			fmt.Fprintf(w, "  subgraph cluster_identity%d {\n", id)
+			fmt.Fprintf(w, "    label=%s;\n", strconv.Quote(identityGraphLabel(endpointIdentity(members[0]))))
+			indent = "    "
+		}
+		for _, ep := range members {
+			label := strings.TrimSpace(fmt.Sprintf("%d %s", ep.ID, endpointName(ep)))
+			fmt.Fprintf(w, "%sendpoint%d [label=%s];\n", indent, ep.ID, strconv.Quote(label))
+		}
+		if id != 0 {
+			fmt.Fprintln(w, "  }")
+		}
+	}
+
+	fmt.Fprintln(w, "  node [shape=ellipse];")
+	for _, entity := range graphEntities {
+		fmt.Fprintf(w, "  %s;\n", entity.name)
+	}
+	for _, id := range ids {
+		for _, ep := range clusters[id] {
+			if ep.Status == nil || ep.Status.Policy == nil || ep.Status.Policy.Realized == nil {
+				continue
+			}
+			realized := ep.Status.Policy.Realized
+			for _, entity := range graphEntities {
+				if containsIdentity(realized.AllowedEgressIdentities, entity.id) {
+					fmt.Fprintf(w, "  endpoint%d -> %s;\n", ep.ID, entity.name)
+				}
+				if containsIdentity(realized.AllowedIngressIdentities, entity.id) {
+					fmt.Fprintf(w, "  %s -> endpoint%d;\n", entity.name, ep.ID)
+				}
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// endpointIdentity returns the identity of ep, or nil if it has none.
+func endpointIdentity(ep *models.Endpoint) *models.Identity {
+	if ep.Status == nil {
+		return nil
+	}
+	return ep.Status.Identity
+}
+
+// identityGraphLabel returns the label of the cluster of identity id: its
+// number followed by its labels, one per line.
+func identityGraphLabel(id *models.Identity) string {
+	label := strconv.FormatInt(identityID(id), 10)
+	if lbls := identityLabels(id); len(lbls) > 0 {
+		label += "\n" + labelsStringWithSep(lbls, "\n")
+	}
+	return label
+}
+
+func containsIdentity(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
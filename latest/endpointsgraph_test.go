@@ -0,0 +1,66 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestWriteEndpointsGraph(t *testing.T) {
+	web := testEndpoint(3, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:tier=frontend")
+	web.Status.Policy = &models.EndpointPolicyStatus{Realized: &models.EndpointPolicy{
+		AllowedIngressIdentities: []int64{2},
+		AllowedEgressIdentities:  []int64{1, 1001},
+	}}
+	eps := []*models.Endpoint{
+		web,
+		testEndpoint(2, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:tier=frontend"),
+		testEndpoint(1, models.EndpointStateReady, 1001, "k8s:app=db"),
+		testEndpoint(4, models.EndpointStateWaitingForIdentity, 0),
+	}
+
+	var buf bytes.Buffer
+	if err := writeEndpointsGraph(&buf, eps); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph endpoints {
+  rankdir=LR;
+  node [shape=box];
+  endpoint4 [label="4"];
+  subgraph cluster_identity1000 {
+    label="1000\nk8s:app=web\nk8s:tier=frontend";
+    endpoint2 [label="2"];
+    endpoint3 [label="3"];
+  }
+  subgraph cluster_identity1001 {
+    label="1001\nk8s:app=db";
+    endpoint1 [label="1"];
+  }
+  node [shape=ellipse];
+  host;
+  world;
+  endpoint3 -> host;
+  world -> endpoint3;
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeEndpointsGraph() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "explain",
+		usage:    "explain step by step how the identity of an endpoint is derived from its labels (-id N)",
+		run:      runExplain,
+		readOnly: true,
+	})
+}
+
+func runExplain(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint")
+	fs.Parse(args)
+
+	if *id == "" {
+		return errors.New("missing endpoint ID, use -id")
+	}
+	ep, err := getEndpoint(ctx, c, *id)
+	if err != nil {
+		return err
+	}
+	explainIdentity(out, ep)
+	return nil
+}
+
+// explainIdentity prints the labels of ep at each step of the derivation of
+// its identity: all labels, the labels left out because they are not
+// security relevant or were disabled, the security relevant labels by
+// source, and the identity allocated for them.
+func explainIdentity(w io.Writer, ep *models.Endpoint) {
+	status := &models.LabelConfigurationStatus{}
+	if ep.Status != nil && ep.Status.Labels != nil {
+		status = ep.Status.Labels
+	}
+	relevant := labels.NewLabelsFromModel(status.SecurityRelevant)
+	derived := labels.NewLabelsFromModel(status.Derived)
+	disabled := labels.NewLabelsFromModel(status.Disabled)
+
+	fmt.Fprintf(w, "1. The endpoint has the labels:\n")
+	printExplainLabels(w, labelsMerge(labelsMerge(relevant, derived), disabled))
+
+	fmt.Fprintf(w, "2. Labels which are not security relevant are left out:\n")
+	printExplainLabels(w, derived)
+	if len(disabled) > 0 {
+		fmt.Fprintf(w, "   Labels disabled by the user are left out as well:\n")
+		printExplainLabels(w, disabled)
+	}
+
+	fmt.Fprintf(w, "3. The identity is derived from the security relevant labels:\n")
+	counts := countBySource(relevant)
+	sources := make([]string, 0, len(counts))
+	for source := range counts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		lbls := relevant.GetFromSource(source)
+		fmt.Fprintf(w, "   from source %s:\n", source)
+		for _, l := range lbls.GetPrintableModel() {
+			fmt.Fprintf(w, "     %s\n", l)
+		}
+	}
+	if reserved := relevant.FindReserved(); len(reserved) > 0 {
+		fmt.Fprintf(w, "   reserved labels take precedence: %s\n", reserved)
+	}
+
+	var identity *models.Identity
+	if ep.Status != nil {
+		identity = ep.Status.Identity
+	}
+	fmt.Fprintf(w, "4. ")
+	switch {
+	case identity == nil:
+		fmt.Fprintf(w, "No identity has been allocated yet\n")
+	case isReservedIdentity(identity.ID):
+		fmt.Fprintf(w, "They map to the reserved identity %d (%s)\n",
+			identity.ID, reservedIdentityName(labels.NewLabelsFromModel(identity.Labels)))
+	default:
+		fmt.Fprintf(w, "They map to the identity %d\n", identity.ID)
+	}
+}
+
+func printExplainLabels(w io.Writer, lbls labels.Labels) {
+	if len(lbls) == 0 {
+		fmt.Fprintf(w, "   (none)\n")
+		return
+	}
+	for _, l := range lbls.GetPrintableModel() {
+		fmt.Fprintf(w, "   %s\n", l)
+	}
+}
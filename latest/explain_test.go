@@ -0,0 +1,90 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestExplainIdentity(t *testing.T) {
+	web := testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:io.kubernetes.pod.namespace=default", "container:id=abc")
+	web.Status.Labels.Derived = []string{"k8s:pod-template-hash=5d4f"}
+	web.Status.Labels.Disabled = []string{"k8s:owner=alice"}
+
+	host := testEndpoint(2, models.EndpointStateReady, 1, "reserved:host")
+
+	tests := []struct {
+		name string
+		ep   *models.Endpoint
+		want string
+	}{
+		{"allocated", web, "" +
+			"1. The endpoint has the labels:\n" +
+			"   container:id=abc\n" +
+			"   k8s:app=web\n" +
+			"   k8s:io.kubernetes.pod.namespace=default\n" +
+			"   k8s:owner=alice\n" +
+			"   k8s:pod-template-hash=5d4f\n" +
+			"2. Labels which are not security relevant are left out:\n" +
+			"   k8s:pod-template-hash=5d4f\n" +
+			"   Labels disabled by the user are left out as well:\n" +
+			"   k8s:owner=alice\n" +
+			"3. The identity is derived from the security relevant labels:\n" +
+			"   from source container:\n" +
+			"     container:id=abc\n" +
+			"   from source k8s:\n" +
+			"     k8s:app=web\n" +
+			"     k8s:io.kubernetes.pod.namespace=default\n" +
+			"4. They map to the identity 1000\n"},
+		{"reserved", host, "" +
+			"1. The endpoint has the labels:\n" +
+			"   reserved:host\n" +
+			"2. Labels which are not security relevant are left out:\n" +
+			"   (none)\n" +
+			"3. The identity is derived from the security relevant labels:\n" +
+			"   from source reserved:\n" +
+			"     reserved:host\n" +
+			"   reserved labels take precedence: reserved:host\n" +
+			"4. They map to the reserved identity 1 (host)\n"},
+		{"no identity", testEndpoint(3, models.EndpointStateWaitingForIdentity, 0), "" +
+			"1. The endpoint has the labels:\n" +
+			"   (none)\n" +
+			"2. Labels which are not security relevant are left out:\n" +
+			"   (none)\n" +
+			"3. The identity is derived from the security relevant labels:\n" +
+			"4. No identity has been allocated yet\n"},
+		{"no status", &models.Endpoint{ID: 4}, "" +
+			"1. The endpoint has the labels:\n" +
+			"   (none)\n" +
+			"2. Labels which are not security relevant are left out:\n" +
+			"   (none)\n" +
+			"3. The identity is derived from the security relevant labels:\n" +
+			"4. No identity has been allocated yet\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			explainIdentity(&buf, tt.ep)
+			if buf.String() != tt.want {
+				t.Errorf("explainIdentity() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
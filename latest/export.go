@@ -0,0 +1,276 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "cilium_client_example"
+
+func init() {
+	register(&command{
+		name:        "export",
+		usage:       "expose endpoint counts as Prometheus metrics",
+		run:         runExport,
+		longRunning: true,
+	})
+}
+
+// endpointMetrics exposes the gauges derived from the endpoint list and the
+// agent configuration. The values are computed on each refresh and swapped in
+// at once, so that a scrape never sees a partially updated set of metrics.
+type endpointMetrics struct {
+	endpointsDesc           *prometheus.Desc
+	endpointStatesDesc      *prometheus.Desc
+	labelSourcesDesc        *prometheus.Desc
+	agentInfoDesc           *prometheus.Desc
+	endpointsByIdentityDesc *prometheus.Desc
+
+	// maxIdentities limits the number of identities endpoints are counted
+	// per, if positive. The labels of each identity are only filled in if
+	// resolveIdentities is set.
+	maxIdentities     int
+	resolveIdentities bool
+
+	mu        sync.Mutex
+	endpoints []prometheus.Metric
+	agentInfo []prometheus.Metric
+}
+
+func newEndpointMetrics(reg prometheus.Registerer) *endpointMetrics {
+	m := &endpointMetrics{
+		endpointsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "endpoints"),
+			"Number of endpoints managed by the agent",
+			nil, nil),
+		endpointStatesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "endpoint_states"),
+			"Number of endpoints per endpoint state",
+			[]string{"state"}, nil),
+		labelSourcesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "endpoint_labels"),
+			"Number of security relevant endpoint labels per label source",
+			[]string{"source"}, nil),
+		agentInfoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "agent_info"),
+			"Datapath and IPAM modes of the agent, always 1",
+			[]string{"datapath_mode", "ipam_mode"}, nil),
+		endpointsByIdentityDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "endpoints_by_identity"),
+			"Number of endpoints per security identity, the least common identities are counted as identity \"other\"",
+			[]string{"identity", "labels"}, nil),
+	}
+	reg.MustRegister(m)
+	return m
+}
+
+// Describe implements prometheus.Collector.
+func (m *endpointMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.endpointsDesc
+	ch <- m.endpointStatesDesc
+	ch <- m.labelSourcesDesc
+	ch <- m.agentInfoDesc
+	ch <- m.endpointsByIdentityDesc
+}
+
+// Collect implements prometheus.Collector. Nothing is collected before the
+// first refresh.
+func (m *endpointMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, metric := range m.endpoints {
+		ch <- metric
+	}
+	for _, metric := range m.agentInfo {
+		ch <- metric
+	}
+}
+
+// update sets all endpoint gauges from eps. Label values not present in eps
+// anymore are dropped.
+func (m *endpointMetrics) update(eps []*models.Endpoint) {
+	states := make(map[string]int)
+	sources := make(map[string]int)
+	for _, ep := range eps {
+		if ep.Status != nil {
+			states[string(ep.Status.State)]++
+		}
+		for source, n := range countBySource(endpointLabels(ep)) {
+			sources[source] += n
+		}
+	}
+	type identityKey struct{ id, labels string }
+	identities := make(map[identityKey]int)
+	for i, count := range countByIdentity(eps) {
+		key := identityKey{strconv.FormatInt(count.id, 10), ""}
+		if m.resolveIdentities {
+			key.labels = count.labels
+		}
+		if m.maxIdentities > 0 && i >= m.maxIdentities {
+			key = identityKey{identityOther, ""}
+		}
+		identities[key] += count.endpoints
+	}
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(m.endpointsDesc, prometheus.GaugeValue, float64(len(eps))),
+	}
+	for state, n := range states {
+		metrics = append(metrics, prometheus.MustNewConstMetric(m.endpointStatesDesc, prometheus.GaugeValue, float64(n), state))
+	}
+	for source, n := range sources {
+		metrics = append(metrics, prometheus.MustNewConstMetric(m.labelSourcesDesc, prometheus.GaugeValue, float64(n), source))
+	}
+	for key, n := range identities {
+		metrics = append(metrics, prometheus.MustNewConstMetric(m.endpointsByIdentityDesc, prometheus.GaugeValue, float64(n), key.id, key.labels))
+	}
+
+	m.mu.Lock()
+	m.endpoints = metrics
+	m.mu.Unlock()
+}
+
+// identityOther is the identity the endpoints of the least common identities
+// are counted under once -max-identity-cardinality is reached.
+const identityOther = "other"
+
+// identityCount is the number of endpoints with a given identity.
+type identityCount struct {
+	id        int64
+	labels    string
+	endpoints int
+}
+
+// countByIdentity returns the number of endpoints of eps per identity, the
+// most common identities first. Endpoints without an identity are left out.
+func countByIdentity(eps []*models.Endpoint) []identityCount {
+	index := make(map[int64]int)
+	var counts []identityCount
+	for _, ep := range eps {
+		if ep.Status == nil || ep.Status.Identity == nil {
+			continue
+		}
+		id := ep.Status.Identity
+		i, ok := index[id.ID]
+		if !ok {
+			i = len(counts)
+			index[id.ID] = i
+			counts = append(counts, identityCount{id: id.ID, labels: labelsToSelectorString(identityLabels(id))})
+		}
+		counts[i].endpoints++
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].endpoints != counts[j].endpoints {
+			return counts[i].endpoints > counts[j].endpoints
+		}
+		return counts[i].id < counts[j].id
+	})
+	return counts
+}
+
+// updateAgentInfo sets the agent info gauge from the agent configuration.
+func (m *endpointMetrics) updateAgentInfo(config *models.DaemonConfiguration) {
+	var metrics []prometheus.Metric
+	if config.Status != nil {
+		metrics = append(metrics, prometheus.MustNewConstMetric(m.agentInfoDesc, prometheus.GaugeValue, 1,
+			string(config.Status.DatapathMode), config.Status.IpamMode))
+	}
+
+	m.mu.Lock()
+	m.agentInfo = metrics
+	m.mu.Unlock()
+}
+
+// configCacheTTL is how long the agent configuration is reused between
+// refreshes of the metrics. It rarely changes, unlike the endpoints.
+const configCacheTTL = time.Minute
+
+func runExport(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	listen := fs.String("listen", ":9999", "address to serve metrics on")
+	interval := fs.Duration("interval", 15*time.Second, "interval at which endpoints are fetched from the agent")
+	resolve := fs.Bool("resolve", false, "add the labels of each identity to the endpoints_by_identity metric")
+	maxIdentities := fs.Int("max-identity-cardinality", 100, "count the endpoints of all but this many most common identities as identity \"other\", 0 for no limit")
+	fs.Parse(args)
+
+	reg := prometheus.NewRegistry()
+	m := newEndpointMetrics(reg)
+	m.maxIdentities, m.resolveIdentities = *maxIdentities, *resolve
+	config := cachedConfig(c, configCacheTTL)
+	go func() {
+		for {
+			if err := refreshEndpointMetrics(ctx, c, config, m); err != nil {
+				log.WithError(client.Hint(err)).Warning("Unable to refresh endpoint metrics")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(*interval):
+			}
+		}
+	}()
+
+	srv := &http.Server{Addr: *listen, Handler: metricsHandler(reg)}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	log.WithField("address", *listen).Info("Serving metrics")
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// metricsHandler serves the metrics gathered from reg on /metrics.
+func metricsHandler(reg *prometheus.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	return mux
+}
+
+func refreshEndpointMetrics(ctx context.Context, c *client.Client, config *configCache, m *endpointMetrics) error {
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	m.update(resp.Payload)
+
+	cfg, err := config.get(ctx)
+	if err != nil {
+		return err
+	}
+	m.updateAgentInfo(cfg)
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEndpointMetricsUpdate(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(3, models.EndpointStateRegenerating, 2000, "k8s:app=db", "container:id=db"),
+		testEndpoint(4, models.EndpointStateWaitingForIdentity, 0),
+	}
+	tests := []struct {
+		name          string
+		maxIdentities int
+		resolve       bool
+		want          string
+	}{
+		{"all identities", 0, false, `
+cilium_client_example_endpoints_by_identity{identity="1000",labels=""} 2
+cilium_client_example_endpoints_by_identity{identity="2000",labels=""} 1
+`},
+		{"resolved", 0, true, `
+cilium_client_example_endpoints_by_identity{identity="1000",labels="k8s:app=web"} 2
+cilium_client_example_endpoints_by_identity{identity="2000",labels="container:id=db,k8s:app=db"} 1
+`},
+		{"max cardinality", 1, true, `
+cilium_client_example_endpoints_by_identity{identity="1000",labels="k8s:app=web"} 2
+cilium_client_example_endpoints_by_identity{identity="other",labels=""} 1
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			m := newEndpointMetrics(reg)
+			m.maxIdentities, m.resolveIdentities = tt.maxIdentities, tt.resolve
+			m.update(eps)
+			m.updateAgentInfo(&models.DaemonConfiguration{Status: &models.DaemonConfigurationStatus{DatapathMode: "veth", IpamMode: "kubernetes"}})
+
+			want := `
+# HELP cilium_client_example_agent_info Datapath and IPAM modes of the agent, always 1
+# TYPE cilium_client_example_agent_info gauge
+cilium_client_example_agent_info{datapath_mode="veth",ipam_mode="kubernetes"} 1
+# HELP cilium_client_example_endpoint_labels Number of security relevant endpoint labels per label source
+# TYPE cilium_client_example_endpoint_labels gauge
+cilium_client_example_endpoint_labels{source="container"} 1
+cilium_client_example_endpoint_labels{source="k8s"} 3
+# HELP cilium_client_example_endpoint_states Number of endpoints per endpoint state
+# TYPE cilium_client_example_endpoint_states gauge
+cilium_client_example_endpoint_states{state="ready"} 2
+cilium_client_example_endpoint_states{state="regenerating"} 1
+cilium_client_example_endpoint_states{state="waiting-for-identity"} 1
+# HELP cilium_client_example_endpoints Number of endpoints managed by the agent
+# TYPE cilium_client_example_endpoints gauge
+cilium_client_example_endpoints 4
+# HELP cilium_client_example_endpoints_by_identity Number of endpoints per security identity, the least common identities are counted as identity "other"
+# TYPE cilium_client_example_endpoints_by_identity gauge` + tt.want
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestExportScrape(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateRegenerating, 1000, "k8s:app=web"),
+	})
+	agent.respond("GET /config", http.StatusOK, &models.DaemonConfiguration{
+		Status: &models.DaemonConfigurationStatus{DatapathMode: "veth", IpamMode: "cluster-pool"},
+	})
+
+	reg := prometheus.NewRegistry()
+	m := newEndpointMetrics(reg)
+	if err := refreshEndpointMetrics(context.Background(), c, cachedConfig(c, configCacheTTL), m); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(metricsHandler(reg))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics = %s\n%s", resp.Status, body)
+	}
+	for _, want := range []string{
+		"# TYPE cilium_client_example_endpoints_by_identity gauge\n",
+		`cilium_client_example_endpoints_by_identity{identity="1000",labels=""} 2` + "\n",
+		"cilium_client_example_endpoints 2\n",
+		`cilium_client_example_endpoint_states{state="ready"} 1` + "\n",
+		`cilium_client_example_endpoint_states{state="regenerating"} 1` + "\n",
+		`cilium_client_example_endpoint_labels{source="k8s"} 2` + "\n",
+		`cilium_client_example_agent_info{datapath_mode="veth",ipam_mode="cluster-pool"} 1` + "\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET /metrics does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestEndpointMetricsConsistentScrapes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := newEndpointMetrics(reg)
+	small := []*models.Endpoint{testEndpoint(1, models.EndpointStateReady, 1000)}
+	large := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000),
+		testEndpoint(2, models.EndpointStateReady, 1000),
+		testEndpoint(3, models.EndpointStateReady, 1000),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			if i%2 == 0 {
+				m.update(small)
+			} else {
+				m.update(large)
+			}
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every scrape must see the gauges of a single update.
+		sums := make(map[string]float64)
+		for _, f := range families {
+			for _, metric := range f.GetMetric() {
+				sums[f.GetName()] += metric.GetGauge().GetValue()
+			}
+		}
+		total := sums["cilium_client_example_endpoints"]
+		for _, name := range []string{"cilium_client_example_endpoint_states", "cilium_client_example_endpoints_by_identity"} {
+			if sums[name] != total {
+				t.Fatalf("%s sums up to %v, but %v endpoints", name, sums[name], total)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+func TestCountByIdentity(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 2000, "k8s:app=db"),
+		testEndpoint(2, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(3, models.EndpointStateReady, 3000, "k8s:app=cache"),
+		testEndpoint(4, models.EndpointStateReady, 3000, "k8s:app=cache"),
+		testEndpoint(5, models.EndpointStateWaitingForIdentity, 0),
+	}
+	want := []identityCount{
+		{id: 3000, labels: "k8s:app=cache", endpoints: 2},
+		{id: 1000, labels: "k8s:app=web", endpoints: 1},
+		{id: 2000, labels: "k8s:app=db", endpoints: 1},
+	}
+	if got := countByIdentity(eps); !reflect.DeepEqual(got, want) {
+		t.Errorf("countByIdentity() = %+v, want %+v", got, want)
+	}
+}
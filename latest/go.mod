@@ -2,7 +2,16 @@ module github.com/cilium/client-example/latest
 
 go 1.16
 
-require github.com/cilium/cilium v1.10.0-rc0.0.20210518163819-4a831f48ea9c
+require (
+	github.com/cilium/cilium v1.10.0-rc0.0.20210518163819-4a831f48ea9c
+	github.com/go-openapi/runtime v0.19.26
+	github.com/go-openapi/strfmt v0.20.0
+	github.com/prometheus/client_golang v1.9.0
+	github.com/prometheus/client_model v0.2.1-0.20200623203004-60555c9708c7
+	github.com/prometheus/common v0.15.0
+	github.com/sirupsen/logrus v1.7.0
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
+)
 
 replace (
 	github.com/miekg/dns => github.com/cilium/dns v1.1.4-0.20190417235132-8e25ec9a0ff3
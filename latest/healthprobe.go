@@ -0,0 +1,109 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/health/client/connectivity"
+	healthModels "github.com/cilium/cilium/api/v1/health/models"
+	"github.com/cilium/cilium/pkg/client"
+	healthClient "github.com/cilium/cilium/pkg/health/client"
+)
+
+func init() {
+	register(&command{
+		name:        "health-probe",
+		usage:       "probe the connectivity to all cluster nodes and print their latencies",
+		run:         runHealthProbe,
+		longRunning: true,
+	})
+}
+
+func runHealthProbe(ctx context.Context, _ *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("health-probe", flag.ExitOnError)
+	probeTimeout := fs.Duration("timeout", time.Minute, "how long to wait for the probe to complete")
+	fs.Parse(args)
+
+	// The connectivity probes are run by cilium-health, which serves its
+	// own API on a separate socket.
+	hc, err := healthClient.NewDefaultClient()
+	if err != nil {
+		return err
+	}
+	hc.SetTransport(loggingTransport{hc.Transport})
+
+	ctx, cancel := context.WithTimeout(ctx, *probeTimeout)
+	defer cancel()
+	// The probe is synchronous, the call returns once all nodes have been
+	// probed.
+	resp, err := hc.Connectivity.PutStatusProbe(connectivity.NewPutStatusProbeParamsWithContext(ctx))
+	if err != nil {
+		return healthClient.Hint(err)
+	}
+
+	printLatencyMatrix(out, resp.Payload)
+	return nil
+}
+
+// printLatencyMatrix prints for each node the latencies of the ICMP and HTTP
+// probes to the node itself and to its health endpoint.
+func printLatencyMatrix(out io.Writer, sr *healthModels.HealthStatusResponse) {
+	nodes := append([]*healthModels.NodeStatus(nil), sr.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "NODE\tHOST ICMP\tHOST HTTP\tENDPOINT ICMP\tENDPOINT HTTP")
+	for _, node := range nodes {
+		name := node.Name
+		if sr.Local != nil && node.Name == sr.Local.Name {
+			name += " (localhost)"
+		}
+		hostICMP, hostHTTP := pathLatencies(healthClient.GetHostPrimaryAddress(node))
+		epICMP, epHTTP := pathLatencies(node.Endpoint)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, hostICMP, hostHTTP, epICMP, epHTTP)
+	}
+	w.Flush()
+}
+
+// pathLatencies formats the latencies of the ICMP and HTTP probes on path.
+func pathLatencies(path *healthModels.PathStatus) (icmp, http string) {
+	if path == nil {
+		return colorize(colorYellow, "-"), colorize(colorYellow, "-")
+	}
+	return probeLatency(path.Icmp), probeLatency(path.HTTP)
+}
+
+// probeLatency formats the latency of a probe. Failed probes are shown as
+// unreachable.
+func probeLatency(cs *healthModels.ConnectivityStatus) string {
+	switch healthClient.GetConnectivityStatusType(cs) {
+	case healthClient.ConnStatusReachable:
+		return colorize(colorGreen, time.Duration(cs.Latency).String())
+	case healthClient.ConnStatusUnreachable:
+		return colorize(colorRed, "UNREACHABLE")
+	}
+	return colorize(colorYellow, "unknown")
+}
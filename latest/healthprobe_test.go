@@ -0,0 +1,77 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	healthModels "github.com/cilium/cilium/api/v1/health/models"
+)
+
+func TestProbeLatency(t *testing.T) {
+	tests := []struct {
+		name string
+		cs   *healthModels.ConnectivityStatus
+		want string
+	}{
+		{"not probed", nil, "unknown"},
+		{"reachable", &healthModels.ConnectivityStatus{Latency: int64(1500 * time.Microsecond)}, "1.5ms"},
+		{"unreachable", &healthModels.ConnectivityStatus{Status: "Connection timed out"}, "UNREACHABLE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := probeLatency(tt.cs); got != tt.want {
+				t.Errorf("probeLatency() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintLatencyMatrix(t *testing.T) {
+	reachable := &healthModels.ConnectivityStatus{Latency: int64(time.Millisecond)}
+	sr := &healthModels.HealthStatusResponse{
+		Local: &healthModels.SelfStatus{Name: "node-a"},
+		Nodes: []*healthModels.NodeStatus{
+			{
+				Name: "node-b",
+				Host: &healthModels.HostStatus{PrimaryAddress: &healthModels.PathStatus{
+					Icmp: reachable,
+					HTTP: &healthModels.ConnectivityStatus{Status: "refused"},
+				}},
+			},
+			{
+				Name:     "node-a",
+				Host:     &healthModels.HostStatus{PrimaryAddress: &healthModels.PathStatus{Icmp: reachable, HTTP: reachable}},
+				Endpoint: &healthModels.PathStatus{Icmp: reachable, HTTP: reachable},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	printLatencyMatrix(&buf, sr)
+	want := "" +
+		"NODE                 HOST ICMP   HOST HTTP     ENDPOINT ICMP   ENDPOINT HTTP\n" +
+		"node-a (localhost)   1ms         1ms           1ms             1ms\n" +
+		"node-b               1ms         UNREACHABLE   -               -\n"
+	if buf.String() != want {
+		t.Errorf("printLatencyMatrix() =\n%s\nwant\n%s", buf.String(), want)
+	}
+	if sr.Nodes[0].Name != "node-b" {
+		t.Error("printLatencyMatrix() reordered the nodes of the response")
+	}
+}
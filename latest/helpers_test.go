@@ -0,0 +1,194 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAgent serves the agent API from canned handlers, keyed by the method
+// and the path of the request without the /v1 prefix, e.g. "GET /healthz".
+// Requests without a handler get a 404 response.
+type fakeAgent struct {
+	// host is the address of the agent, as given with -sockets.
+	host string
+
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	calls    map[string]int
+}
+
+// newFakeAgent starts a fake agent for the duration of the test and returns
+// a client connected to it.
+func newFakeAgent(t *testing.T) (*fakeAgent, *client.Client) {
+	t.Helper()
+	a := &fakeAgent{
+		handlers: make(map[string]http.HandlerFunc),
+		calls:    make(map[string]int),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/v1")
+		a.mu.Lock()
+		h := a.handlers[route]
+		a.calls[route]++
+		a.mu.Unlock()
+		if h == nil {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	a.host = "tcp://" + srv.Listener.Addr().String()
+	c, err := newClient(clientOptions{host: a.host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, c
+}
+
+// handle serves route with h.
+func (a *fakeAgent) handle(route string, h http.HandlerFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers[route] = h
+}
+
+// respond serves route with body encoded as JSON and the given status.
+func (a *fakeAgent) respond(route string, status int, body interface{}) {
+	a.handle(route, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+// callCount returns the number of requests made for route.
+func (a *fakeAgent) callCount(route string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.calls[route]
+}
+
+// testEndpoint returns an endpoint as listed by the agent, with the given
+// security relevant labels. An identity of 0 leaves the endpoint without one.
+func testEndpoint(id int64, state models.EndpointState, identity int64, lbls ...string) *models.Endpoint {
+	ep := &models.Endpoint{
+		ID: id,
+		Status: &models.EndpointStatus{
+			State:  state,
+			Labels: &models.LabelConfigurationStatus{SecurityRelevant: lbls},
+		},
+	}
+	if identity != 0 {
+		ep.Status.Identity = &models.Identity{ID: identity, Labels: lbls}
+	}
+	return ep
+}
+
+// withAddress adds an address pair to ep, either of which may be empty.
+func withAddress(ep *models.Endpoint, ipv4, ipv6 string) *models.Endpoint {
+	if ep.Status.Networking == nil {
+		ep.Status.Networking = &models.EndpointNetworking{}
+	}
+	ep.Status.Networking.Addressing = append(ep.Status.Networking.Addressing, &models.AddressPair{IPV4: ipv4, IPV6: ipv6})
+	return ep
+}
+
+// logCapture records the entries logged during a test.
+type logCapture struct {
+	mu      sync.Mutex
+	entries []logrus.Entry
+}
+
+// captureLogs records the entries logged until the end of the test instead
+// of writing them out.
+func captureLogs(t *testing.T) *logCapture {
+	c := &logCapture{}
+	logger := logging.DefaultLogger
+	out, hooks := logger.Out, logger.ReplaceHooks(logrus.LevelHooks{})
+	logger.AddHook(c)
+	logger.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		logger.SetOutput(out)
+		logger.ReplaceHooks(hooks)
+	})
+	return c
+}
+
+func (c *logCapture) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (c *logCapture) Fire(e *logrus.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, *e)
+	return nil
+}
+
+// withMessage returns the entries logged with msg.
+func (c *logCapture) withMessage(msg string) []logrus.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var entries []logrus.Entry
+	for _, e := range c.entries {
+		if e.Message == msg {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// withStdin makes the command read input from stdin and hides the prompts
+// it writes to stderr for the duration of the test.
+func withStdin(t *testing.T, input string) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin, oldStderr := os.Stdin, os.Stderr
+	os.Stdin, os.Stderr = stdin, stderr
+	t.Cleanup(func() {
+		os.Stdin, os.Stderr = oldStdin, oldStderr
+		stdin.Close()
+		stderr.Close()
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+// identityResolver looks up the labels of security identities, querying the
+// agent at most once per identity.
+type identityResolver struct {
+	c     *client.Client
+	cache map[int64]labels.Labels
+}
+
+func newIdentityResolver(c *client.Client) *identityResolver {
+	return &identityResolver{
+		c:     c,
+		cache: make(map[int64]labels.Labels),
+	}
+}
+
+// resolve returns the labels of identity id. Identities unknown to the agent
+// resolve to nil labels without an error.
+func (r *identityResolver) resolve(ctx context.Context, id int64) (labels.Labels, error) {
+	if lbls, ok := r.cache[id]; ok {
+		return lbls, nil
+	}
+
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	params := policy.NewGetIdentityIDParamsWithContext(ctx).WithID(strconv.FormatInt(id, 10))
+	resp, err := r.c.Policy.GetIdentityID(params)
+	var notFound *policy.GetIdentityIDNotFound
+	switch {
+	case errors.As(err, &notFound):
+		r.cache[id] = nil
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	lbls := labels.NewLabelsFromModel(resp.Payload.Labels)
+	r.cache[id] = lbls
+	return lbls, nil
+}
+
+// minAllocatedIdentity is the lowest numeric identity handed out by the
+// identity allocator. Identities below are reserved for well-known entities
+// such as the host or the world.
+const minAllocatedIdentity = 256
+
+// isReservedIdentity returns true if id is one of the reserved identities.
+func isReservedIdentity(id int64) bool {
+	return id > 0 && id < minAllocatedIdentity
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	register(&command{
+		name:        "identity-audit",
+		usage:       "watch the endpoints and log every change of their identity",
+		run:         runIdentityAudit,
+		longRunning: true,
+	})
+}
+
+// identityChange is a change of the identity of an endpoint between two
+// polls.
+type identityChange struct {
+	endpointID int64
+	old, new   *models.Identity
+}
+
+// auditMaxRetryInterval bounds the backoff of identity-audit while the agent
+// cannot be reached.
+const auditMaxRetryInterval = time.Minute
+
+func runIdentityAudit(ctx context.Context, c *client.Client, _ io.Writer, args []string) error {
+	fs := flag.NewFlagSet("identity-audit", flag.ExitOnError)
+	interval := fs.Duration("interval", 5*time.Second, "interval at which endpoints are fetched from the agent")
+	maxFailures := fs.Int("max-failures", 0, "give up after this many consecutive failures to reach the agent, 0 to keep retrying")
+	fs.Parse(args)
+
+	// identities is the last known good state of the endpoints. It is kept
+	// while the agent cannot be reached, so that the changes made in the
+	// meantime are reported once it is back.
+	identities := make(map[int64]*models.Identity)
+	failures, backoff := 0, *interval
+	for {
+		delay := *interval
+		eps, err := pollEndpoints(ctx, c)
+		switch {
+		case err == nil:
+			if failures > 0 {
+				log.WithField("failures", failures).Info("Agent reachable again, resuming identity audit")
+				failures, backoff = 0, *interval
+			}
+			for _, change := range auditIdentityChanges(identities, eps) {
+				logIdentityChange(change)
+			}
+		case !isRetryable(err):
+			return err
+		default:
+			failures++
+			if *maxFailures > 0 && failures >= *maxFailures {
+				return fmt.Errorf("giving up after %d consecutive failures: %w", failures, err)
+			}
+			// Back off while the agent is unavailable, e.g. restarting.
+			delay = backoff
+			if backoff < auditMaxRetryInterval {
+				if backoff *= 2; backoff > auditMaxRetryInterval {
+					backoff = auditMaxRetryInterval
+				}
+			}
+			log.WithError(client.Hint(err)).WithField("retryIn", delay).Warning("Unable to list endpoints")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func pollEndpoints(ctx context.Context, c *client.Client) ([]*models.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// auditIdentityChanges returns the endpoints of eps whose identity differs
+// from the one recorded in identities, and records their current identity.
+// Endpoints seen for the first time are recorded without being reported, and
+// endpoints which are gone are forgotten.
+func auditIdentityChanges(identities map[int64]*models.Identity, eps []*models.Endpoint) []identityChange {
+	var changes []identityChange
+	seen := make(map[int64]struct{}, len(eps))
+	for _, ep := range eps {
+		seen[ep.ID] = struct{}{}
+		var id *models.Identity
+		if ep.Status != nil {
+			id = ep.Status.Identity
+		}
+		old, known := identities[ep.ID]
+		identities[ep.ID] = id
+		if known && identityID(old) != identityID(id) {
+			changes = append(changes, identityChange{endpointID: ep.ID, old: old, new: id})
+		}
+	}
+	for epID := range identities {
+		if _, ok := seen[epID]; !ok {
+			delete(identities, epID)
+		}
+	}
+	return changes
+}
+
+// identityID returns the numeric identity of id, 0 if the endpoint has none.
+func identityID(id *models.Identity) int64 {
+	if id == nil {
+		return 0
+	}
+	return id.ID
+}
+
+func identityLabels(id *models.Identity) labels.Labels {
+	if id == nil {
+		return labels.Labels{}
+	}
+	return labels.NewLabelsFromModel(id.Labels)
+}
+
+func logIdentityChange(change identityChange) {
+	oldLabels, newLabels := identityLabels(change.old), identityLabels(change.new)
+	log.WithFields(logrus.Fields{
+		logfields.EndpointID:  change.endpointID,
+		logfields.OldIdentity: identityID(change.old),
+		logfields.Identity:    identityID(change.new),
+		"addedLabels":         labelsDifference(newLabels, oldLabels).GetPrintableModel(),
+		"removedLabels":       labelsDifference(oldLabels, newLabels).GetPrintableModel(),
+	}).Info("Endpoint identity changed")
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+func TestAuditIdentityChanges(t *testing.T) {
+	first := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateReady, 2000, "k8s:app=db"),
+		testEndpoint(3, models.EndpointStateReady, 3000, "k8s:app=cache"),
+	}
+	second := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateRegenerating, 2001, "k8s:app=db", "k8s:tier=backend"),
+		testEndpoint(4, models.EndpointStateWaitingForIdentity, 0),
+	}
+
+	identities := make(map[int64]*models.Identity)
+	if changes := auditIdentityChanges(identities, first); len(changes) != 0 {
+		t.Errorf("first poll reported changes: %v", changes)
+	}
+	changes := auditIdentityChanges(identities, second)
+	want := []identityChange{{endpointID: 2, old: first[1].Status.Identity, new: second[1].Status.Identity}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("auditIdentityChanges() = %v, want %v", changes, want)
+	}
+
+	// Endpoint 3 is gone and endpoint 4 was recorded without an identity.
+	wantIdentities := map[int64]*models.Identity{
+		1: second[0].Status.Identity,
+		2: second[1].Status.Identity,
+		4: nil,
+	}
+	if !reflect.DeepEqual(identities, wantIdentities) {
+		t.Errorf("identities = %v, want %v", identities, wantIdentities)
+	}
+	if changes := auditIdentityChanges(identities, second); len(changes) != 0 {
+		t.Errorf("unchanged poll reported changes: %v", changes)
+	}
+}
+
+// auditAgent returns a fake agent failing the first failures polls of the
+// endpoints, then listing endpoint 1 with identity 1000 once and with
+// identity 2000 afterwards.
+func auditAgent(t *testing.T, failures int) (*fakeAgent, *client.Client) {
+	agent, c := newFakeAgent(t)
+	var mu sync.Mutex
+	polls := 0
+	agent.handle("GET /endpoint", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		n := polls
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case n <= failures:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case n == failures+1:
+			writeJSON(w, []*models.Endpoint{testEndpoint(1, models.EndpointStateReady, 1000)}, true)
+		default:
+			writeJSON(w, []*models.Endpoint{testEndpoint(1, models.EndpointStateReady, 2000)}, true)
+		}
+	})
+	return agent, c
+}
+
+func TestIdentityAuditRecovers(t *testing.T) {
+	logs := captureLogs(t)
+	agent, c := auditAgent(t, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for agent.callCount("GET /endpoint") < 5 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	err := runIdentityAudit(ctx, c, nil, []string{"-interval", "1ms", "-max-failures", "3"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runIdentityAudit() = %v, want %v", err, context.Canceled)
+	}
+
+	if n := len(logs.withMessage("Unable to list endpoints")); n != 2 {
+		t.Errorf("%d failures logged, want 2", n)
+	}
+	if resumed := logs.withMessage("Agent reachable again, resuming identity audit"); len(resumed) != 1 || resumed[0].Data["failures"] != 2 {
+		t.Errorf("resumption logged as %v, want once after 2 failures", resumed)
+	}
+	changes := logs.withMessage("Endpoint identity changed")
+	if len(changes) != 1 {
+		t.Fatalf("%d identity changes logged, want 1", len(changes))
+	}
+	if old, id := changes[0].Data[logfields.OldIdentity], changes[0].Data[logfields.Identity]; old != int64(1000) || id != int64(2000) {
+		t.Errorf("identity change logged as %v -> %v, want 1000 -> 2000", old, id)
+	}
+}
+
+func TestIdentityAuditMaxFailures(t *testing.T) {
+	captureLogs(t)
+	agent, c := auditAgent(t, 2)
+
+	err := runIdentityAudit(context.Background(), c, nil, []string{"-interval", "1ms", "-max-failures", "2"})
+	if err == nil || !strings.Contains(err.Error(), "giving up after 2 consecutive failures") {
+		t.Errorf("runIdentityAudit() = %v, want to give up after 2 failures", err)
+	}
+	if n := agent.callCount("GET /endpoint"); n != 2 {
+		t.Errorf("endpoints polled %d times, want 2", n)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/client/ipam"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// ipamOwner is the owner recorded by the agent for addresses allocated by
+// this client.
+var ipamOwner = "client-example"
+
+func init() {
+	register(&command{
+		name:  "ipam",
+		usage: "allocate or release IP addresses (allocate [-ip IP] | release -ip IP)",
+		run:   runIPAM,
+	})
+}
+
+func runIPAM(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing action, must be one of: allocate, release")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("ipam "+action, flag.ExitOnError)
+	ip := fs.String("ip", "", "IP address to allocate or release")
+	family := fs.String("family", client.AddressFamilyIPv4, "address family of an automatically allocated address (ipv4 or ipv6)")
+	fs.Parse(args)
+
+	switch action {
+	case "allocate":
+		if *ip == "" {
+			return ipamAllocateAuto(ctx, c, out, *family)
+		}
+		return ipamAllocateIP(ctx, c, out, *ip)
+	case "release":
+		if *ip == "" {
+			return errors.New("release requires -ip")
+		}
+		return ipamRelease(ctx, c, out, *ip)
+	default:
+		return fmt.Errorf("unknown action %q, must be one of: allocate, release", action)
+	}
+}
+
+// ipamAllocateAuto lets the agent pick the next free address of the given
+// family and prints the address that was handed back.
+func ipamAllocateAuto(ctx context.Context, c *client.Client, out io.Writer, family string) error {
+	params := ipam.NewPostIpamParamsWithContext(ctx).WithFamily(&family).WithOwner(&ipamOwner)
+	resp, err := c.Ipam.PostIpam(params)
+	if err != nil {
+		return ipamError("", err)
+	}
+
+	var ips []string
+	for _, a := range []*models.IPAMAddressResponse{resp.Payload.IPV4, resp.Payload.IPV6} {
+		if a != nil && a.IP != "" {
+			ips = append(ips, a.IP)
+		}
+	}
+	fmt.Fprintf(out, "Allocated %s\n", strings.Join(ips, ", "))
+	return printIPAMPool(ctx, c, out)
+}
+
+// ipamAllocateIP asks the agent to allocate the specific address ip.
+func ipamAllocateIP(ctx context.Context, c *client.Client, out io.Writer, ip string) error {
+	params := ipam.NewPostIpamIPParamsWithContext(ctx).WithIP(ip).WithOwner(&ipamOwner)
+	if _, err := c.Ipam.PostIpamIP(params); err != nil {
+		return ipamError(ip, err)
+	}
+	fmt.Fprintf(out, "Allocated %s\n", ip)
+	return printIPAMPool(ctx, c, out)
+}
+
+// ipamRelease returns ip to the pool.
+func ipamRelease(ctx context.Context, c *client.Client, out io.Writer, ip string) error {
+	if _, err := c.Ipam.DeleteIpamIP(ipam.NewDeleteIpamIPParamsWithContext(ctx).WithIP(ip)); err != nil {
+		return ipamError(ip, err)
+	}
+	fmt.Fprintf(out, "Released %s\n", ip)
+	return printIPAMPool(ctx, c, out)
+}
+
+// ipamError translates the IPAM API errors into messages a user can act
+// upon. Errors not specific to IPAM are returned unchanged.
+func ipamError(ip string, err error) error {
+	var (
+		exists      *ipam.PostIpamIPExists
+		ipFailure   *ipam.PostIpamIPFailure
+		autoFailure *ipam.PostIpamFailure
+		notFound    *ipam.DeleteIpamIPNotFound
+	)
+	switch {
+	case errors.As(err, &exists):
+		return fmt.Errorf("address %s is already allocated", ip)
+	case errors.As(err, &ipFailure):
+		return ipamFailure(string(ipFailure.Payload))
+	case errors.As(err, &autoFailure):
+		return ipamFailure(string(autoFailure.Payload))
+	case errors.As(err, &notFound):
+		return fmt.Errorf("address %s is not allocated", ip)
+	}
+	return err
+}
+
+// ipamFailure wraps an allocation failure reported by the agent, calling out
+// the case where the pool has run out of addresses.
+func ipamFailure(msg string) error {
+	if strings.Contains(msg, "range is full") {
+		return fmt.Errorf("address pool exhausted: %s", msg)
+	}
+	return fmt.Errorf("allocation failed: %s", msg)
+}
+
+// printIPAMPool prints the state of the pool as reported by the agent and
+// the addresses currently allocated out of it.
+func printIPAMPool(ctx context.Context, c *client.Client, out io.Writer) error {
+	resp, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	st := resp.Payload.Ipam
+	if st == nil {
+		fmt.Fprintln(out, "IPAM status not available")
+		return nil
+	}
+
+	if st.Status != "" {
+		fmt.Fprintf(out, "Pool: %s\n", st.Status)
+	}
+	fmt.Fprintf(out, "%d addresses allocated:\n", len(st.Allocations))
+	ips := make([]string, 0, len(st.Allocations))
+	for ip := range st.Allocations {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	for _, ip := range ips {
+		fmt.Fprintf(out, "  %s\t%s\n", ip, st.Allocations[ip])
+	}
+	return nil
+}
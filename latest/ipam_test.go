@@ -0,0 +1,116 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// fakeIPAMAgent returns a fake agent whose pool has the given allocations.
+func fakeIPAMAgent(t *testing.T, allocations models.AllocationMap) (*fakeAgent, *client.Client) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Ipam: &models.IPAMStatus{Status: "10.0.0.0/24", Allocations: allocations},
+	})
+	return agent, c
+}
+
+func TestIPAM(t *testing.T) {
+	allocations := models.AllocationMap{"10.0.0.5": "client-example", "10.0.0.1": "router"}
+	tests := []struct {
+		name   string
+		route  string
+		status int
+		body   interface{}
+		args   []string
+		want   string
+	}{
+		{"allocate", "POST /ipam", http.StatusCreated, &models.IPAMResponse{
+			Address: &models.AddressPair{},
+			IPV4:    &models.IPAMAddressResponse{IP: "10.0.0.5"},
+		}, []string{"allocate"}, "Allocated 10.0.0.5\n"},
+		{"allocate ip", "POST /ipam/10.0.0.5", http.StatusOK, nil,
+			[]string{"allocate", "-ip", "10.0.0.5"}, "Allocated 10.0.0.5\n"},
+		{"release", "DELETE /ipam/10.0.0.7", http.StatusOK, nil,
+			[]string{"release", "-ip", "10.0.0.7"}, "Released 10.0.0.7\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, c := fakeIPAMAgent(t, allocations)
+			agent.respond(tt.route, tt.status, tt.body)
+
+			var out bytes.Buffer
+			if err := runIPAM(context.Background(), c, &out, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want + "Pool: 10.0.0.0/24\n" +
+				"2 addresses allocated:\n" +
+				"  10.0.0.1\trouter\n" +
+				"  10.0.0.5\tclient-example\n"
+			if out.String() != want {
+				t.Errorf("runIPAM() =\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestIPAMErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   string
+		status  int
+		body    interface{}
+		args    []string
+		wantErr string
+	}{
+		{"exists", "POST /ipam/10.0.0.5", http.StatusConflict, nil,
+			[]string{"allocate", "-ip", "10.0.0.5"}, "address 10.0.0.5 is already allocated"},
+		{"out of range", "POST /ipam/10.1.0.5", http.StatusInternalServerError, "IP 10.1.0.5 not in range",
+			[]string{"allocate", "-ip", "10.1.0.5"}, "allocation failed: IP 10.1.0.5 not in range"},
+		{"exhausted", "POST /ipam", http.StatusBadGateway, "range is full",
+			[]string{"allocate"}, "address pool exhausted: range is full"},
+		{"not allocated", "DELETE /ipam/10.0.0.7", http.StatusNotFound, nil,
+			[]string{"release", "-ip", "10.0.0.7"}, "address 10.0.0.7 is not allocated"},
+		{"release without ip", "", 0, nil,
+			[]string{"release"}, "release requires -ip"},
+		{"unknown action", "", 0, nil,
+			[]string{"renew"}, `unknown action "renew", must be one of: allocate, release`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, c := fakeIPAMAgent(t, nil)
+			if tt.route != "" {
+				agent.respond(tt.route, tt.status, tt.body)
+			}
+
+			var out bytes.Buffer
+			err := runIPAM(context.Background(), c, &out, tt.args)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("runIPAM() error = %v, want %s", err, tt.wantErr)
+			}
+			if out.Len() != 0 {
+				t.Errorf("runIPAM() printed %q on error", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "ip-conflicts",
+		usage:    "report addresses assigned to more than one endpoint, exits with code 1 if there are any",
+		run:      runIPConflicts,
+		readOnly: true,
+	})
+}
+
+// ipConflict is an address assigned to several endpoints.
+type ipConflict struct {
+	ip  string
+	ids []int64
+}
+
+func runIPConflicts(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("ip-conflicts", flag.ExitOnError)
+	fs.Parse(args)
+
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	conflicts := findIPConflicts(resp.Payload)
+	if len(conflicts) == 0 {
+		fmt.Fprintln(out, "No address is assigned to more than one endpoint")
+		return nil
+	}
+	for _, conflict := range conflicts {
+		ids := make([]string, 0, len(conflict.ids))
+		for _, id := range conflict.ids {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		fmt.Fprintf(out, "Address %s is assigned to endpoints %s\n", conflict.ip, strings.Join(ids, ", "))
+	}
+	return errUnhealthy
+}
+
+// findIPConflicts returns the IPv4 and IPv6 addresses assigned to more than
+// one of eps, sorted by address, each with the sorted IDs of the endpoints.
+func findIPConflicts(eps []*models.Endpoint) []ipConflict {
+	owners := make(map[string][]int64)
+	for _, ep := range eps {
+		v4s, v6s := endpointAddresses(ep)
+		for _, ip := range append(v4s, v6s...) {
+			owners[ip] = append(owners[ip], ep.ID)
+		}
+	}
+
+	var conflicts []ipConflict
+	for ip, ids := range owners {
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		conflicts = append(conflicts, ipConflict{ip: ip, ids: ids})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].ip < conflicts[j].ip
+	})
+	return conflicts
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestFindIPConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		eps  []*models.Endpoint
+		want []ipConflict
+	}{
+		{"none", []*models.Endpoint{
+			withAddress(testEndpoint(1, models.EndpointStateReady, 0), "10.0.0.1", "fd00::1"),
+			withAddress(testEndpoint(2, models.EndpointStateReady, 0), "10.0.0.2", "fd00::2"),
+			testEndpoint(3, models.EndpointStateWaitingForIdentity, 0),
+		}, nil},
+		{"shared IPv4", []*models.Endpoint{
+			withAddress(testEndpoint(7, models.EndpointStateReady, 0), "10.0.0.1", "fd00::7"),
+			withAddress(testEndpoint(2, models.EndpointStateReady, 0), "10.0.0.1", "fd00::2"),
+		}, []ipConflict{{ip: "10.0.0.1", ids: []int64{2, 7}}}},
+		{"sorted by address", []*models.Endpoint{
+			withAddress(testEndpoint(1, models.EndpointStateReady, 0), "10.0.0.9", "fd00::1"),
+			withAddress(testEndpoint(2, models.EndpointStateReady, 0), "10.0.0.9", "fd00::1"),
+			withAddress(testEndpoint(3, models.EndpointStateReady, 0), "10.0.0.9", ""),
+		}, []ipConflict{{ip: "10.0.0.9", ids: []int64{1, 2, 3}}, {ip: "fd00::1", ids: []int64{1, 2}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findIPConflicts(tt.eps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findIPConflicts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPConflicts(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		withAddress(testEndpoint(1, models.EndpointStateReady, 0), "10.0.0.1", ""),
+		withAddress(testEndpoint(2, models.EndpointStateReady, 0), "10.0.0.1", ""),
+	})
+
+	var buf bytes.Buffer
+	err := runIPConflicts(context.Background(), c, &buf, nil)
+	if !errors.Is(err, errUnhealthy) {
+		t.Errorf("runIPConflicts() = %v, want %v", err, errUnhealthy)
+	}
+	if got, want := buf.String(), "Address 10.0.0.1 is assigned to endpoints 1, 2\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
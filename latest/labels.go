@@ -0,0 +1,421 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+// endpointLabels returns the security relevant labels of ep, i.e. the labels
+// its identity is derived from.
+func endpointLabels(ep *models.Endpoint) labels.Labels {
+	if ep.Status == nil || ep.Status.Labels == nil {
+		return labels.Labels{}
+	}
+	return labels.NewLabelsFromModel(ep.Status.Labels.SecurityRelevant)
+}
+
+// countBySource returns the number of labels in lbls per label source.
+func countBySource(lbls labels.Labels) map[string]int {
+	counts := make(map[string]int)
+	for _, l := range lbls {
+		counts[l.Source]++
+	}
+	return counts
+}
+
+// newLabelsFromK8sLabels converts the labels of a Kubernetes object, as found
+// in its metadata, into labels of source k8s. It is the inverse of
+// labels.Labels.K8sStringMap for labels of that source. Dots in a key are
+// never interpreted as a source prefix, e.g. io.kubernetes.pod.namespace is
+// kept whole. As with labels.NewLabel though, a prefix ending with a colon is
+// dropped, which valid Kubernetes label keys never have.
+func newLabelsFromK8sLabels(m map[string]string) labels.Labels {
+	lbls := make(labels.Labels, len(m))
+	for k, v := range m {
+		l := labels.NewLabel(k, v, labels.LabelSourceK8s)
+		lbls[l.Key] = l
+	}
+	return lbls
+}
+
+// labelArrayFromMap converts m into a label array of the given source, sorted
+// by key, without going through the string form of each label. As with
+// labels.NewLabel, a source prefix embedded in a key, e.g. "k8s:app", is
+// dropped in favor of source.
+func labelArrayFromMap(m map[string]string, source string) labels.LabelArray {
+	ls := make(labels.LabelArray, 0, len(m))
+	for k, v := range m {
+		ls = append(ls, labels.NewLabel(k, v, source))
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		if ls[i].Key != ls[j].Key {
+			return ls[i].Key < ls[j].Key
+		}
+		return ls[i].Value < ls[j].Value
+	})
+	return ls
+}
+
+// labelsEqualsIgnoreValue returns true if l and other contain labels with the
+// same keys and sources, regardless of their values. As with
+// labels.Label.Equals, a label of source any in l matches a label of any
+// source in other.
+func labelsEqualsIgnoreValue(l, other labels.Labels) bool {
+	if len(l) != len(other) {
+		return false
+	}
+
+	for k, lbl1 := range l {
+		if lbl2, ok := other[k]; ok {
+			if (lbl1.IsAnySource() || lbl1.Source == lbl2.Source) && lbl1.Key == lbl2.Key {
+				continue
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// labelsFilter returns the labels of l for which pred returns true. Unlike
+// labels.Labels.GetFromSource, it can select labels on any of their fields,
+// e.g. only the labels with a value.
+func labelsFilter(l labels.Labels, pred func(labels.Label) bool) labels.Labels {
+	res := make(labels.Labels, len(l))
+	for k, lbl := range l {
+		if pred(lbl) {
+			res[k] = lbl
+		}
+	}
+	return res
+}
+
+// labelsWithout returns the labels of l which are not of the given source,
+// the inverse of labels.Labels.GetFromSource. Sources are compared as they
+// are, so labels.LabelSourceAny only removes the labels of source any.
+func labelsWithout(l labels.Labels, source string) labels.Labels {
+	return labelsFilter(l, func(lbl labels.Label) bool {
+		return lbl.Source != source
+	})
+}
+
+// labelsWithoutReserved returns the labels of l which are not of source
+// reserved, such as reserved:init.
+func labelsWithoutReserved(l labels.Labels) labels.Labels {
+	return labelsWithout(l, labels.LabelSourceReserved)
+}
+
+// labelsEqualsIgnoringReserved returns true if l and other contain the same
+// labels once the reserved ones are left out.
+func labelsEqualsIgnoringReserved(l, other labels.Labels) bool {
+	return labelsWithoutReserved(l).Equals(labelsWithoutReserved(other))
+}
+
+// labelsIntersect returns the labels contained in both l and other.
+func labelsIntersect(l, other labels.Labels) labels.Labels {
+	res := labels.Labels{}
+	for k, lbl1 := range l {
+		if lbl2, ok := other[k]; ok && lbl1.Equals(&lbl2) {
+			res[k] = lbl1
+		}
+	}
+	return res
+}
+
+// labelsDifference returns the labels of l which are not contained in
+// other.
+func labelsDifference(l, other labels.Labels) labels.Labels {
+	res := labels.Labels{}
+	for k, lbl1 := range l {
+		if lbl2, ok := other[k]; !ok || !lbl1.Equals(&lbl2) {
+			res[k] = lbl1
+		}
+	}
+	return res
+}
+
+// labelMatchesPattern returns true if target matches the pattern l. As with
+// labels.Label.Equals, a pattern of source any matches labels of any source.
+// In addition, a pattern value of "*" matches any value of target, e.g.
+// "k8s:app=*" matches all labels with key app of source k8s.
+func labelMatchesPattern(l, target *labels.Label) bool {
+	if !l.IsAnySource() && l.Source != target.Source {
+		return false
+	}
+	return l.Key == target.Key && (l.Value == "*" || l.Value == target.Value)
+}
+
+// labelsContain returns true if every label in required matches a label of
+// l according to labelMatchesPattern, i.e. required labels of source any
+// match labels of any source. An empty required always matches.
+func labelsContain(l labels.Labels, required labels.LabelArray) bool {
+	for i := range required {
+		lbl, ok := l[required[i].Key]
+		if !ok || !labelMatchesPattern(&required[i], &lbl) {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedIdentityName returns the name of the reserved identity lbls map
+// to, e.g. labels.IDNameHost, or an empty string if lbls do not belong to a
+// reserved identity. The host may carry node labels in addition to
+// reserved:host, so only the reserved labels are considered.
+func reservedIdentityName(lbls labels.Labels) string {
+	switch {
+	case labelsContain(lbls, labels.LabelHost.LabelArray()):
+		return labels.IDNameHost
+	case labelsContain(lbls, labels.LabelHealth.LabelArray()):
+		return labels.IDNameHealth
+	}
+	for _, name := range []string{
+		labels.IDNameWorld,
+		labels.IDNameRemoteNode,
+		labels.IDNameCluster,
+		labels.IDNameInit,
+		labels.IDNameUnmanaged,
+		labels.IDNameUnknown,
+		labels.IDNameNone,
+	} {
+		if l, ok := lbls[name]; ok && l.Source == labels.LabelSourceReserved {
+			return name
+		}
+	}
+	return ""
+}
+
+// labelsStringWithSep returns the labels of l in the form "source:key=value",
+// sorted and joined with sep. With a comma, it is the same as
+// labels.Labels.String, other separators render e.g. one label per line.
+func labelsStringWithSep(l labels.Labels, sep string) string {
+	return strings.Join(l.GetPrintableModel(), sep)
+}
+
+// labelsToSelectorString returns l as a comma separated list of label
+// selectors of the form "source:key=value", sorted, which the -l flag parses
+// back into the same labels. Labels with an empty value are rendered with a
+// trailing equal sign so that they are not taken as existence selectors.
+func labelsToSelectorString(l labels.Labels) string {
+	sels := make([]string, 0, len(l))
+	for _, lbl := range l {
+		sels = append(sels, lbl.Source+":"+lbl.Key+"="+lbl.Value)
+	}
+	sort.Strings(sels)
+	return strings.Join(sels, ",")
+}
+
+// knownLabelSources are the label sources canonicalizeLabels recognizes.
+var knownLabelSources = []string{
+	labels.LabelSourceUnspec,
+	labels.LabelSourceAny,
+	labels.LabelSourceK8s,
+	labels.LabelSourceMesos,
+	labels.LabelSourceContainer,
+	labels.LabelSourceReserved,
+	labels.LabelSourceCIDR,
+	labels.LabelSourceCiliumGenerated,
+}
+
+// sourceIsKnown returns true if source is one of the labels.LabelSource
+// constants naming an actual source of labels, i.e. neither
+// labels.LabelSourceAny nor labels.LabelSourceUnspec.
+func sourceIsKnown(source string) bool {
+	for _, known := range knownSources() {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+// knownSources returns the label sources sourceIsKnown accepts.
+func knownSources() []string {
+	var sources []string
+	for _, source := range knownLabelSources {
+		if source != labels.LabelSourceAny && source != labels.LabelSourceUnspec {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// warnUnknownSources logs a warning for every label of l whose source is
+// not known. Labels without a source, or matching any source, are fine.
+func warnUnknownSources(l labels.Labels) {
+	labelsForEachSorted(l, func(lbl labels.Label) {
+		if lbl.Source == labels.LabelSourceAny || lbl.Source == labels.LabelSourceUnspec || sourceIsKnown(lbl.Source) {
+			return
+		}
+		log.WithField("label", lbl.String()).Warningf("Unknown label source %q, must be one of: %s",
+			lbl.Source, strings.Join(knownSources(), ", "))
+	})
+}
+
+// canonicalizeLabels returns a copy of l with surrounding whitespace trimmed
+// from sources, keys and values, and known sources spelled as the
+// corresponding labels.LabelSource constant regardless of case. Keys without
+// a source which use the "$host" shorthand for reserved labels, e.g. because
+// whitespace kept labels.ParseLabel from recognizing it, are turned into
+// reserved labels. Labels which are equivalent once cleaned up end up with
+// the same key, so the result has a stable labels.Labels.SHA256Sum whatever
+// path the labels were parsed from.
+func canonicalizeLabels(l labels.Labels) labels.Labels {
+	res := make(labels.Labels, len(l))
+	for _, lbl := range l {
+		source := strings.TrimSpace(lbl.Source)
+		for _, known := range knownLabelSources {
+			if strings.EqualFold(source, known) {
+				source = known
+				break
+			}
+		}
+		key := strings.TrimSpace(lbl.Key)
+		if (source == "" || source == labels.LabelSourceUnspec) && strings.HasPrefix(key, "$") {
+			source, key = labels.LabelSourceReserved, key[1:]
+		}
+		res[key] = labels.Label{Source: source, Key: key, Value: strings.TrimSpace(lbl.Value)}
+	}
+	return res
+}
+
+// validateLabels returns an error naming every label of l which has an empty
+// key, which is stored under a map key other than its own key, or which
+// contains characters that would make labels.Label.FormatForKVStore
+// ambiguous: a semicolon anywhere, a colon in the source or an equal sign in
+// the key.
+func validateLabels(l labels.Labels) error {
+	var problems []string
+	for _, k := range labelsKeys(l) {
+		lbl := l[k]
+		switch {
+		case !lbl.IsValid():
+			problems = append(problems, fmt.Sprintf("%q: empty key", k))
+		case k != lbl.Key:
+			problems = append(problems, fmt.Sprintf("%q: stored under key %q", lbl.Key, k))
+		case strings.Contains(lbl.Source+lbl.Key+lbl.Value, ";"):
+			problems = append(problems, fmt.Sprintf("%q: contains a semicolon", lbl.String()))
+		case strings.Contains(lbl.Source, ":"):
+			problems = append(problems, fmt.Sprintf("%q: source contains a colon", lbl.String()))
+		case strings.Contains(lbl.Key, "="):
+			problems = append(problems, fmt.Sprintf("%q: key contains an equal sign", lbl.String()))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid labels: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// labelsKeys returns the keys of l in sorted order, or nil if l is nil.
+func labelsKeys(l labels.Labels) []string {
+	if l == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelsValues returns the values of the labels of l in sorted order, or nil
+// if l is nil. Labels without a value contribute an empty string.
+func labelsValues(l labels.Labels) []string {
+	if l == nil {
+		return nil
+	}
+	values := make([]string, 0, len(l))
+	for _, lbl := range l {
+		values = append(values, lbl.Value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// labelsForEachSorted calls fn for each label of l in the order of their
+// keys, so that rendering labels does not depend on the map iteration order.
+func labelsForEachSorted(l labels.Labels, fn func(labels.Label)) {
+	for _, k := range labelsKeys(l) {
+		fn(l[k])
+	}
+}
+
+// labelsMerge returns a copy of l with the labels of other added. Labels of
+// other replace the labels of l with the same key.
+func labelsMerge(l, other labels.Labels) labels.Labels {
+	res := make(labels.Labels, len(l)+len(other))
+	for k, lbl := range l {
+		res[k] = lbl
+	}
+	for k, lbl := range other {
+		res[k] = lbl
+	}
+	return res
+}
+
+// labelsRemove returns a copy of l without the labels whose keys are found in
+// other. The sources and values of the labels in other are ignored.
+func labelsRemove(l, other labels.Labels) labels.Labels {
+	res := labels.Labels{}
+	for k, lbl := range l {
+		if _, ok := other[k]; !ok {
+			res[k] = lbl
+		}
+	}
+	return res
+}
+
+// labelsSubtract returns a copy of l without the labels with the given keys.
+// Keys not found in l are ignored, and l is left untouched.
+func labelsSubtract(l labels.Labels, keys ...string) labels.Labels {
+	res := make(labels.Labels, len(l))
+	for k, lbl := range l {
+		res[k] = lbl
+	}
+	for _, k := range keys {
+		delete(res, k)
+	}
+	return res
+}
+
+// parseLabelFromKVStoreFormat parses a label in the format returned by
+// labels.Label.FormatForKVStore, i.e. "source:key=value;". The source ends at
+// the first colon and the key at the first equal sign, so the value may
+// itself contain colons and equal signs.
+func parseLabelFromKVStoreFormat(s string) (labels.Label, error) {
+	if !strings.HasSuffix(s, ";") {
+		return labels.Label{}, fmt.Errorf("invalid label %q: missing trailing semicolon", s)
+	}
+	str := strings.TrimSuffix(s, ";")
+	i := strings.IndexByte(str, ':')
+	if i < 0 {
+		return labels.Label{}, fmt.Errorf("invalid label %q: missing source", s)
+	}
+	source, rest := str[:i], str[i+1:]
+	j := strings.IndexByte(rest, '=')
+	if j < 0 {
+		return labels.Label{}, fmt.Errorf("invalid label %q: missing value", s)
+	}
+	return labels.Label{Source: source, Key: rest[:j], Value: rest[j+1:]}, nil
+}
@@ -0,0 +1,499 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func TestNewLabelsFromK8sLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]string
+		want labels.Labels
+		// lossy is set if K8sStringMap does not give m back.
+		lossy bool
+	}{
+		{"empty", map[string]string{}, labels.Labels{}, false},
+		{"plain key", map[string]string{"app": "web"}, labels.Labels{
+			"app": {Key: "app", Value: "web", Source: labels.LabelSourceK8s},
+		}, false},
+		{"dotted key", map[string]string{"io.kubernetes.pod.namespace": "default"}, labels.Labels{
+			"io.kubernetes.pod.namespace": {Key: "io.kubernetes.pod.namespace", Value: "default", Source: labels.LabelSourceK8s},
+		}, false},
+		{"prefixed key", map[string]string{"app.kubernetes.io/name": "web", "tier": ""}, labels.Labels{
+			"app.kubernetes.io/name": {Key: "app.kubernetes.io/name", Value: "web", Source: labels.LabelSourceK8s},
+			"tier":                   {Key: "tier", Source: labels.LabelSourceK8s},
+		}, false},
+		{"colon", map[string]string{"container:app": "web"}, labels.Labels{
+			"app": {Key: "app", Value: "web", Source: labels.LabelSourceK8s},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLabelsFromK8sLabels(tt.m)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newLabelsFromK8sLabels() = %v, want %v", got, tt.want)
+			}
+			if !tt.lossy {
+				if back := got.K8sStringMap(); !reflect.DeepEqual(back, tt.m) {
+					t.Errorf("K8sStringMap() = %v, want %v", back, tt.m)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelsForEachSorted(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:zone=a", "container:app=web", "reserved:host"})
+	var keys []string
+	labelsForEachSorted(l, func(lbl labels.Label) {
+		keys = append(keys, lbl.Key)
+	})
+	if want := []string{"app", "host", "zone"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("labelsForEachSorted() visited %v, want %v", keys, want)
+	}
+
+	labelsForEachSorted(nil, func(lbl labels.Label) {
+		t.Errorf("labelsForEachSorted(nil) visited %v", lbl)
+	})
+}
+
+func TestLabelsFilter(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:canary", "reserved:host"})
+	tests := []struct {
+		name string
+		pred func(labels.Label) bool
+		want []string
+	}{
+		{"all", func(labels.Label) bool { return true }, []string{"k8s:app=web", "k8s:canary", "reserved:host"}},
+		{"none", func(labels.Label) bool { return false }, nil},
+		{"with value", func(lbl labels.Label) bool { return lbl.Value != "" }, []string{"k8s:app=web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := labelsFilter(l, tt.pred)
+			if !reflect.DeepEqual(got.GetPrintableModel(), tt.want) {
+				t.Errorf("labelsFilter() = %v, want %v", got.GetPrintableModel(), tt.want)
+			}
+		})
+	}
+	if len(l) != 3 {
+		t.Errorf("labelsFilter() modified its input: %v", l)
+	}
+}
+
+func TestLabelsWithout(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:app=web", "container:debug", "reserved:host"})
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{labels.LabelSourceReserved, []string{"container:debug", "k8s:app=web"}},
+		{labels.LabelSourceK8s, []string{"container:debug", "reserved:host"}},
+		// Sources are compared as they are, any does not match all of them.
+		{labels.LabelSourceAny, []string{"container:debug", "k8s:app=web", "reserved:host"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := labelsWithout(l, tt.source).GetPrintableModel(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labelsWithout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelsStringWithSep(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:tier=frontend", "k8s:app=web", "reserved:host"})
+	tests := []struct {
+		sep  string
+		want string
+	}{
+		{",", l.String()},
+		{"\n", "k8s:app=web\nk8s:tier=frontend\nreserved:host"},
+		{" ", "k8s:app=web k8s:tier=frontend reserved:host"},
+	}
+	for _, tt := range tests {
+		if got := labelsStringWithSep(l, tt.sep); got != tt.want {
+			t.Errorf("labelsStringWithSep(%q) = %q, want %q", tt.sep, got, tt.want)
+		}
+	}
+	if got := labelsStringWithSep(labels.Labels{}, "\n"); got != "" {
+		t.Errorf("labelsStringWithSep() of no labels = %q, want empty", got)
+	}
+}
+
+func TestLabelsEqualsIgnoreValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		l, other []string
+		want     bool
+	}{
+		{"same", []string{"k8s:app=web"}, []string{"k8s:app=web"}, true},
+		{"other value", []string{"k8s:app=web"}, []string{"k8s:app=db"}, true},
+		{"other source", []string{"k8s:app=web"}, []string{"container:app=web"}, false},
+		{"any source", []string{"any:app=web"}, []string{"container:app=db"}, true},
+		{"other key", []string{"k8s:app=web"}, []string{"k8s:name=web"}, false},
+		{"subset", []string{"k8s:app=web"}, []string{"k8s:app=web", "k8s:tier=frontend"}, false},
+		{"empty", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, other := labels.NewLabelsFromModel(tt.l), labels.NewLabelsFromModel(tt.other)
+			if got := labelsEqualsIgnoreValue(l, other); got != tt.want {
+				t.Errorf("labelsEqualsIgnoreValue(%v, %v) = %t, want %t", l, other, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelMatchesPattern(t *testing.T) {
+	tests := []struct {
+		pattern, target string
+		want            bool
+	}{
+		{"k8s:app=web", "k8s:app=web", true},
+		{"k8s:app=web", "k8s:app=db", false},
+		{"k8s:app=*", "k8s:app=db", true},
+		{"k8s:app=*", "k8s:app", true},
+		{"k8s:app=*", "container:app=web", false},
+		{"any:app=*", "container:app=web", true},
+		{"k8s:app=*", "k8s:name=web", false},
+		{"k8s:app=we*", "k8s:app=web", false},
+	}
+	for _, tt := range tests {
+		pattern, target := labels.ParseLabel(tt.pattern), labels.ParseLabel(tt.target)
+		if got := labelMatchesPattern(&pattern, &target); got != tt.want {
+			t.Errorf("labelMatchesPattern(%s, %s) = %t, want %t", tt.pattern, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestLabelsContain(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend", "container:debug"})
+	tests := []struct {
+		required []string
+		want     bool
+	}{
+		{nil, true},
+		{[]string{"k8s:app=web"}, true},
+		{[]string{"k8s:app=web", "k8s:tier=frontend"}, true},
+		{[]string{"k8s:app=web", "k8s:tier=backend"}, false},
+		{[]string{"any:app=web"}, true},
+		{[]string{"container:app=web"}, false},
+		{[]string{"k8s:tier=*", "container:debug"}, true},
+		{[]string{"k8s:owner=*"}, false},
+	}
+	for _, tt := range tests {
+		if got := labelsContain(l, labels.ParseLabelArray(tt.required...)); got != tt.want {
+			t.Errorf("labelsContain(%v) = %t, want %t", tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestReservedIdentityName(t *testing.T) {
+	tests := []struct {
+		lbls []string
+		want string
+	}{
+		{[]string{"reserved:host"}, labels.IDNameHost},
+		{[]string{"reserved:host", "k8s:node-role.kubernetes.io/master"}, labels.IDNameHost},
+		{[]string{"reserved:health"}, labels.IDNameHealth},
+		{[]string{"reserved:world"}, labels.IDNameWorld},
+		{[]string{"reserved:init"}, labels.IDNameInit},
+		{[]string{"k8s:world"}, ""},
+		{[]string{"k8s:app=web"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := reservedIdentityName(labels.NewLabelsFromModel(tt.lbls)); got != tt.want {
+			t.Errorf("reservedIdentityName(%v) = %q, want %q", tt.lbls, got, tt.want)
+		}
+	}
+}
+
+func TestParseLabelFromKVStoreFormat(t *testing.T) {
+	for _, lbl := range []labels.Label{
+		{Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		{Source: labels.LabelSourceReserved, Key: "host"},
+		{Source: labels.LabelSourceK8s, Key: "io.kubernetes.pod.namespace", Value: "default"},
+		{Source: labels.LabelSourceCIDR, Key: "fd00::/64"},
+		{Source: labels.LabelSourceK8s, Key: "url", Value: "http://example.com/?a=b"},
+	} {
+		got, err := parseLabelFromKVStoreFormat(string(lbl.FormatForKVStore()))
+		if err != nil {
+			t.Errorf("parseLabelFromKVStoreFormat(%q) = %v", lbl.FormatForKVStore(), err)
+			continue
+		}
+		if got != lbl {
+			t.Errorf("parseLabelFromKVStoreFormat(%q) = %#v, want %#v", lbl.FormatForKVStore(), got, lbl)
+		}
+	}
+
+	for _, s := range []string{"k8s:app=web", "app=web;", "k8s:app;"} {
+		if _, err := parseLabelFromKVStoreFormat(s); err == nil {
+			t.Errorf("parseLabelFromKVStoreFormat(%q) succeeded", s)
+		}
+	}
+}
+
+func TestLabelsToSelectorString(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:tier=frontend", "k8s:app=web", "k8s:canary"})
+	want := "k8s:app=web,k8s:canary=,k8s:tier=frontend"
+	if got := labelsToSelectorString(l); got != want {
+		t.Errorf("labelsToSelectorString() = %q, want %q", got, want)
+	}
+
+	// The selectors select the labels they were built from, and not those
+	// with another value for canary.
+	sels := parseLabelSelectors(want)
+	if !matchesAll(sels, l) {
+		t.Errorf("selectors %q do not match %v", want, l)
+	}
+	other := labels.NewLabelsFromModel([]string{"k8s:tier=frontend", "k8s:app=web", "k8s:canary=true"})
+	if matchesAll(sels, other) {
+		t.Errorf("selectors %q match %v", want, other)
+	}
+}
+
+func TestCanonicalizeLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		l    labels.Labels
+		want labels.Labels
+	}{
+		{"trimmed", labels.Labels{
+			" app ": {Source: " k8s ", Key: " app ", Value: " web "},
+		}, labels.Labels{
+			"app": {Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		}},
+		{"source case", labels.Labels{
+			"app": {Source: "K8S", Key: "app", Value: "web"},
+		}, labels.Labels{
+			"app": {Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		}},
+		{"unknown source", labels.Labels{
+			"app": {Source: "Custom", Key: "app", Value: "web"},
+		}, labels.Labels{
+			"app": {Source: "Custom", Key: "app", Value: "web"},
+		}},
+		{"equivalent labels", labels.Labels{
+			"app":  {Source: "k8s", Key: "app", Value: "web"},
+			"app ": {Source: "K8s", Key: "app ", Value: "web"},
+		}, labels.Labels{
+			"app": {Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := canonicalizeLabels(tt.l)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("canonicalizeLabels() = %#v, want %#v", got, tt.want)
+			}
+			if got.SHA256Sum() != tt.want.SHA256Sum() {
+				t.Errorf("canonicalizeLabels() has checksum %s, want %s", got.SHA256Sum(), tt.want.SHA256Sum())
+			}
+		})
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		l       labels.Labels
+		wantErr string
+	}{
+		{"valid", labels.NewLabelsFromModel([]string{"k8s:app=web", "reserved:host"}), ""},
+		{"empty key", labels.Labels{"": {Source: "k8s", Value: "web"}}, `"": empty key`},
+		{"other map key", labels.Labels{"name": {Source: "k8s", Key: "app", Value: "web"}}, `"app": stored under key "name"`},
+		{"semicolon", labels.Labels{"app": {Source: "k8s", Key: "app", Value: "web;db"}}, "contains a semicolon"},
+		{"colon in source", labels.Labels{"app": {Source: "k8s:x", Key: "app", Value: "web"}}, "source contains a colon"},
+		{"equal sign in key", labels.Labels{"a=b": {Source: "k8s", Key: "a=b", Value: "web"}}, "key contains an equal sign"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLabels(tt.l)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateLabels() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validateLabels() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// All problems are reported at once.
+	err := validateLabels(labels.Labels{
+		"a=b": {Source: "k8s", Key: "a=b"},
+		"app": {Source: "k8s", Key: "app", Value: "web;db"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "equal sign") || !strings.Contains(err.Error(), "semicolon") {
+		t.Errorf("validateLabels() = %v, want both problems", err)
+	}
+}
+
+func TestSourceIsKnown(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{labels.LabelSourceK8s, true},
+		{labels.LabelSourceReserved, true},
+		{labels.LabelSourceCIDR, true},
+		{labels.LabelSourceCiliumGenerated, true},
+		{labels.LabelSourceAny, false},
+		{labels.LabelSourceUnspec, false},
+		{"K8s", false},
+		{"custom", false},
+	}
+	for _, tt := range tests {
+		if got := sourceIsKnown(tt.source); got != tt.want {
+			t.Errorf("sourceIsKnown(%q) = %t, want %t", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestWarnUnknownSources(t *testing.T) {
+	logs := captureLogs(t)
+	warnUnknownSources(labels.Labels{
+		"app":   {Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		"tier":  {Source: labels.LabelSourceAny, Key: "tier"},
+		"zone":  {Source: "custom", Key: "zone", Value: "a"},
+		"owner": {Source: "team", Key: "owner", Value: "b"},
+	})
+	var warned []string
+	for _, e := range logs.entries {
+		warned = append(warned, e.Data["label"].(string))
+	}
+	if want := []string{"team:owner=b", "custom:zone=a"}; !reflect.DeepEqual(warned, want) {
+		t.Errorf("warned about %v, want %v", warned, want)
+	}
+}
+
+func TestLabelsKeysValues(t *testing.T) {
+	tests := []struct {
+		name         string
+		l            labels.Labels
+		keys, values []string
+	}{
+		{"nil", nil, nil, nil},
+		{"empty", labels.Labels{}, []string{}, []string{}},
+		{"sorted", labels.NewLabelsFromModel([]string{"k8s:tier=frontend", "k8s:app=web", "reserved:host"}),
+			[]string{"app", "host", "tier"}, []string{"", "frontend", "web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelsKeys(tt.l); !reflect.DeepEqual(got, tt.keys) {
+				t.Errorf("labelsKeys() = %#v, want %#v", got, tt.keys)
+			}
+			if got := labelsValues(tt.l); !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("labelsValues() = %#v, want %#v", got, tt.values)
+			}
+		})
+	}
+}
+
+func TestLabelsEqualsIgnoringReserved(t *testing.T) {
+	tests := []struct {
+		name     string
+		l, other []string
+		want     bool
+	}{
+		{"same", []string{"k8s:app=web"}, []string{"k8s:app=web"}, true},
+		{"reserved on one side", []string{"k8s:app=web", "reserved:init"}, []string{"k8s:app=web"}, true},
+		{"different reserved", []string{"reserved:host"}, []string{"reserved:world"}, true},
+		{"other value", []string{"k8s:app=web", "reserved:init"}, []string{"k8s:app=db"}, false},
+		{"only reserved", []string{"reserved:init"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, other := labels.NewLabelsFromModel(tt.l), labels.NewLabelsFromModel(tt.other)
+			if got := labelsEqualsIgnoringReserved(l, other); got != tt.want {
+				t.Errorf("labelsEqualsIgnoringReserved(%v, %v) = %t, want %t", l, other, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelArrayFromMap(t *testing.T) {
+	got := labelArrayFromMap(map[string]string{
+		"tier":                        "frontend",
+		"app":                         "web",
+		"io.kubernetes.pod.namespace": "default",
+	}, labels.LabelSourceK8s)
+	want := labels.LabelArray{
+		{Source: labels.LabelSourceK8s, Key: "app", Value: "web"},
+		{Source: labels.LabelSourceK8s, Key: "io.kubernetes.pod.namespace", Value: "default"},
+		{Source: labels.LabelSourceK8s, Key: "tier", Value: "frontend"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labelArrayFromMap() = %v, want %v", got, want)
+	}
+	// Equivalent to parsing the string form of each label.
+	if parsed := labels.ParseLabelArray("k8s:app=web", "k8s:io.kubernetes.pod.namespace=default", "k8s:tier=frontend"); !got.Equals(parsed) {
+		t.Errorf("labelArrayFromMap() = %v, want %v as parsed", got, parsed)
+	}
+
+	if got := labelArrayFromMap(nil, labels.LabelSourceK8s); len(got) != 0 {
+		t.Errorf("labelArrayFromMap(nil) = %v, want empty", got)
+	}
+}
+
+func TestLabelsSubtract(t *testing.T) {
+	l := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend", "reserved:host"})
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{"no keys", nil, []string{"k8s:app=web", "k8s:tier=frontend", "reserved:host"}},
+		{"some keys", []string{"tier", "host"}, []string{"k8s:app=web"}},
+		{"unknown key", []string{"owner", "app"}, []string{"k8s:tier=frontend", "reserved:host"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelsSubtract(l, tt.keys...).GetPrintableModel(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labelsSubtract(%v) = %v, want %v", tt.keys, got, tt.want)
+			}
+		})
+	}
+	if len(l) != 3 {
+		t.Errorf("labelsSubtract() modified its input: %v", l)
+	}
+}
+
+func TestLabelsIntersectDifference(t *testing.T) {
+	a := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend", "reserved:init"})
+	b := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=backend", "k8s:zone=a"})
+	if got, want := labelsIntersect(a, b).GetPrintableModel(), []string{"k8s:app=web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labelsIntersect() = %v, want %v", got, want)
+	}
+	// A label whose value differs is on both sides of the difference.
+	if got, want := labelsDifference(a, b).GetPrintableModel(), []string{"k8s:tier=frontend", "reserved:init"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labelsDifference(a, b) = %v, want %v", got, want)
+	}
+	if got, want := labelsDifference(b, a).GetPrintableModel(), []string{"k8s:tier=backend", "k8s:zone=a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labelsDifference(b, a) = %v, want %v", got, want)
+	}
+	if got := labelsDifference(a, a); len(got) != 0 {
+		t.Errorf("labelsDifference(a, a) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "labels-diff",
+		usage:    "compare the security relevant labels of two endpoints (-a ID -b ID)",
+		run:      runLabelsDiff,
+		readOnly: true,
+	})
+}
+
+func runLabelsDiff(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("labels-diff", flag.ExitOnError)
+	a := fs.String("a", "", "ID of the first endpoint")
+	b := fs.String("b", "", "ID of the second endpoint")
+	ignoreReserved := fs.Bool("ignore-reserved", false, "leave out reserved labels, such as reserved:init, from the comparison")
+	fs.Parse(args)
+
+	if *a == "" || *b == "" {
+		return errors.New("missing endpoint IDs, use -a and -b")
+	}
+
+	epA, err := getEndpoint(ctx, c, *a)
+	if err != nil {
+		return err
+	}
+	epB, err := getEndpoint(ctx, c, *b)
+	if err != nil {
+		return err
+	}
+
+	lblsA, lblsB := endpointLabels(epA), endpointLabels(epB)
+	if *ignoreReserved {
+		if labelsEqualsIgnoringReserved(lblsA, lblsB) {
+			fmt.Fprintln(out, "Endpoints have the same labels, ignoring reserved labels")
+			return nil
+		}
+		lblsA, lblsB = labelsWithoutReserved(lblsA), labelsWithoutReserved(lblsB)
+	}
+	printLabelsDiff(out, *a, *b, lblsA, lblsB)
+	return nil
+}
+
+// getEndpoint returns the endpoint with the given ID.
+func getEndpoint(ctx context.Context, c *client.Client, id string) (*models.Endpoint, error) {
+	resp, err := c.Endpoint.GetEndpointID(endpoint.NewGetEndpointIDParamsWithContext(ctx).WithID(id))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// printLabelsDiff prints the labels common to a and b, followed by the labels
+// only found on either side.
+func printLabelsDiff(w io.Writer, nameA, nameB string, a, b labels.Labels) {
+	printLabelsSection(w, "Common labels", labelsIntersect(a, b))
+	printLabelsSection(w, fmt.Sprintf("Only on endpoint %s", nameA), labelsDifference(a, b))
+	printLabelsSection(w, fmt.Sprintf("Only on endpoint %s", nameB), labelsDifference(b, a))
+}
+
+func printLabelsSection(w io.Writer, header string, lbls labels.Labels) {
+	fmt.Fprintf(w, "%s (%d):\n", header, len(lbls))
+	for _, l := range lbls.GetPrintableModel() {
+		fmt.Fprintf(w, "  %s\n", l)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestLabelsDiff(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint/1", http.StatusOK, testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web", "k8s:tier=frontend"))
+	agent.respond("GET /endpoint/2", http.StatusOK, testEndpoint(2, models.EndpointStateReady, 1001, "k8s:app=web", "reserved:init"))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"all labels", nil, "" +
+			"Common labels (1):\n  k8s:app=web\n" +
+			"Only on endpoint 1 (1):\n  k8s:tier=frontend\n" +
+			"Only on endpoint 2 (1):\n  reserved:init\n"},
+		{"ignore reserved", []string{"-ignore-reserved"}, "" +
+			"Common labels (1):\n  k8s:app=web\n" +
+			"Only on endpoint 1 (1):\n  k8s:tier=frontend\n" +
+			"Only on endpoint 2 (0):\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := runLabelsDiff(context.Background(), c, &out, append([]string{"-a", "1", "-b", "2"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runLabelsDiff() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelsDiffSameIgnoringReserved(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint/1", http.StatusOK, testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"))
+	agent.respond("GET /endpoint/2", http.StatusOK, testEndpoint(2, models.EndpointStateReady, 1000, "k8s:app=web", "reserved:init"))
+
+	var out bytes.Buffer
+	if err := runLabelsDiff(context.Background(), c, &out, []string{"-a", "1", "-b", "2", "-ignore-reserved"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Endpoints have the same labels, ignoring reserved labels\n"; out.String() != want {
+		t.Errorf("runLabelsDiff() = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/go-openapi/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "client-example")
+
+// setupLogging configures the level and format of the default logger. It must
+// be called before any API call is made.
+func setupLogging(level, format string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	formatter := logging.GetFormatter(logging.LogFormat(format))
+	if formatter == nil {
+		return fmt.Errorf("unknown log format %q, must be one of: %s, %s",
+			format, logging.LogFormatText, logging.LogFormatJSON)
+	}
+	logging.DefaultLogger.SetFormatter(formatter)
+	logging.SetLogLevel(lvl)
+	return nil
+}
+
+// loggingTransport wraps the transport of the API client to log every API
+// call at debug level.
+type loggingTransport struct {
+	runtime.ClientTransport
+}
+
+func (t loggingTransport) Submit(op *runtime.ClientOperation) (interface{}, error) {
+	start := time.Now()
+	result, err := t.ClientTransport.Submit(op)
+
+	scopedLog := log.WithFields(logrus.Fields{
+		"method":           op.Method,
+		"path":             op.PathPattern,
+		"operation":        op.ID,
+		logfields.Duration: time.Since(start),
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		scopedLog.WithError(err).WithField("status", "timeout").Error("API call timed out")
+	case err != nil:
+		scopedLog.WithError(err).WithField("status", "failed").Debug("API call")
+	default:
+		scopedLog.WithField("status", "ok").Debug("API call")
+	}
+	return result, err
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// withLogLevel sets the level of the default logger for the duration of the
+// test.
+func withLogLevel(t *testing.T, level logrus.Level) {
+	logger := logging.DefaultLogger
+	old, formatter := logger.GetLevel(), logger.Formatter
+	logger.SetLevel(level)
+	t.Cleanup(func() {
+		logger.SetLevel(old)
+		logger.SetFormatter(formatter)
+	})
+}
+
+func TestSetupLogging(t *testing.T) {
+	withLogLevel(t, logrus.InfoLevel)
+	tests := []struct {
+		level, format string
+		want          logrus.Level
+		wantErr       bool
+	}{
+		{"debug", "text", logrus.DebugLevel, false},
+		{"warning", "json", logrus.WarnLevel, false},
+		{"verbose", "text", logrus.InfoLevel, true},
+		{"debug", "xml", logrus.InfoLevel, true},
+	}
+	for _, tt := range tests {
+		logging.DefaultLogger.SetLevel(logrus.InfoLevel)
+		err := setupLogging(tt.level, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("setupLogging(%q, %q) error = %v, wantErr %v", tt.level, tt.format, err, tt.wantErr)
+		}
+		if got := logging.DefaultLogger.GetLevel(); got != tt.want {
+			t.Errorf("setupLogging(%q, %q) set level %s, want %s", tt.level, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	withLogLevel(t, logrus.DebugLevel)
+	logs := captureLogs(t)
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{})
+	agent.handle("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	if _, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParams()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Daemon.GetConfig(daemon.NewGetConfigParamsWithContext(ctx)); err == nil {
+		t.Fatal("GetConfig() succeeded past its deadline")
+	}
+
+	calls := logs.withMessage("API call")
+	if len(calls) != 1 {
+		t.Fatalf("logged %d API calls, want 1", len(calls))
+	}
+	if e := calls[0]; e.Level != logrus.DebugLevel || e.Data["path"] != "/healthz" || e.Data["status"] != "ok" {
+		t.Errorf("API call logged as %s %v", e.Level, e.Data)
+	}
+	timeouts := logs.withMessage("API call timed out")
+	if len(timeouts) != 1 {
+		t.Fatalf("logged %d timed out API calls, want 1", len(timeouts))
+	}
+	if e := timeouts[0]; e.Level != logrus.ErrorLevel || e.Data["path"] != "/config" || e.Data["status"] != "timeout" {
+		t.Errorf("timed out API call logged as %s %v", e.Level, e.Data)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "lookup-ip",
+		usage:    "print the endpoints owning IPv4 or IPv6 addresses (IP...)",
+		run:      runLookupIP,
+		readOnly: true,
+	})
+}
+
+func runLookupIP(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("lookup-ip", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("missing IP addresses")
+	}
+	ips := make([]net.IP, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		ip := net.ParseIP(arg)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", arg)
+		}
+		ips = append(ips, ip)
+	}
+
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	index := endpointsByIP(resp.Payload)
+
+	tw := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(tw, "IP\tENDPOINT\tCONTAINER\tLABELS")
+	for _, ip := range ips {
+		ep, ok := index[ip.String()]
+		if !ok {
+			fmt.Fprintf(tw, "%s\tnot found\t\t\n", ip)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", ip, ep.ID, endpointContainerName(ep), endpointLabels(ep))
+	}
+	return tw.Flush()
+}
+
+// endpointsByIP indexes eps by their IPv4 and IPv6 addresses, in the form
+// returned by net.IP.String so that equivalent IPv6 notations match.
+func endpointsByIP(eps []*models.Endpoint) map[string]*models.Endpoint {
+	index := make(map[string]*models.Endpoint)
+	for _, ep := range eps {
+		v4s, v6s := endpointAddresses(ep)
+		for _, addr := range append(v4s, v6s...) {
+			if ip := net.ParseIP(addr); ip != nil {
+				index[ip.String()] = ep
+			}
+		}
+	}
+	return index
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestEndpointsByIP(t *testing.T) {
+	eps := []*models.Endpoint{
+		withAddress(testEndpoint(1, models.EndpointStateReady, 0), "10.0.0.1", "fd00::1"),
+		withAddress(testEndpoint(2, models.EndpointStateReady, 0), "", "fd00:0:0::2"),
+		withAddress(testEndpoint(3, models.EndpointStateReady, 0), "not an address", ""),
+	}
+	index := endpointsByIP(eps)
+	tests := []struct {
+		ip   string
+		want int64
+	}{
+		{"10.0.0.1", 1},
+		{"fd00::1", 1},
+		{"fd00::2", 2},
+		{"10.0.0.2", 0},
+	}
+	for _, tt := range tests {
+		var got int64
+		if ep, ok := index[tt.ip]; ok {
+			got = ep.ID
+		}
+		if got != tt.want {
+			t.Errorf("endpointsByIP()[%q] = endpoint %d, want %d", tt.ip, got, tt.want)
+		}
+	}
+	if len(index) != 3 {
+		t.Errorf("endpointsByIP() indexed %d addresses, want 3", len(index))
+	}
+}
+
+func TestLookupIP(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	ep := withAddress(testEndpoint(1, models.EndpointStateReady, 0, "k8s:app=web"), "10.0.0.1", "fd00::1")
+	ep.Status.ExternalIdentifiers = &models.EndpointIdentifiers{ContainerName: "web"}
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{ep})
+
+	var buf bytes.Buffer
+	if err := runLookupIP(context.Background(), c, &buf, []string{"fd00:0::1", "10.0.0.2"}); err != nil {
+		t.Fatalf("runLookupIP() = %v", err)
+	}
+	want := "" +
+		"IP         ENDPOINT    CONTAINER   LABELS\n" +
+		"fd00::1    1           web         k8s:app=web\n" +
+		"10.0.0.2   not found               \n"
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLookupIPInvalid(t *testing.T) {
+	// Arguments are checked before any API call, so no client is needed.
+	if err := runLookupIP(context.Background(), nil, &bytes.Buffer{}, []string{"10.0.0.256"}); err == nil {
+		t.Error("runLookupIP() succeeded with an invalid address")
+	}
+}
@@ -17,47 +17,277 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/cilium/cilium/pkg/client"
 )
 
+// command is a subcommand of the example client. Each subcommand parses its
+// own flags from args, talks to the agent through c and writes its results
+// to out. All API calls must be made with ctx so that they are bound by the
+// -timeout deadline.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c *client.Client, out io.Writer, args []string) error
+
+	// longRunning commands are not bound by the -timeout deadline as a
+	// whole. Instead, they must apply it to each of their API calls.
+	longRunning bool
+
+	// readOnly commands do not change the state of the agent. Only they can
+	// be run against several agents at once with -sockets.
+	readOnly bool
+}
+
+var commands = map[string]*command{}
+
+// register makes cmd available as a subcommand. It is meant to be called from
+// the init function of the file implementing the subcommand.
+func register(cmd *command) {
+	commands[cmd.name] = cmd
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command] [command flags]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-12s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nWithout a command, %q is run.\n\nFlags:\n", defaultCommand)
+	flag.PrintDefaults()
+}
+
+// defaultCommand is run when no subcommand is given on the command line.
+const defaultCommand = "endpoints"
+
+// exitTimeout is the exit code used when the agent did not answer an API
+// call before the -timeout deadline.
+const exitTimeout = 3
+
+var (
+	logLevel  = flag.String("log-level", "info", "log level, one of: debug, info, warn, error")
+	logFormat = flag.String("log-format", "text", "log format, one of: text, json")
+	timeout   = flag.Duration("timeout", 30*time.Second, "deadline for all API calls made by the command")
+
+	timeoutPerCall = flag.Duration("timeout-per-call", 0, "deadline for each API call of commands making several independent calls, within -timeout (0 for none)")
+
+	debugHTTP     = flag.Bool("debug-http", false, "dump the request line, status and duration of every HTTP call to stderr")
+	debugHTTPBody = flag.Bool("debug-http-body", false, "dump the bodies of HTTP calls along with -debug-http, they may hold sensitive data")
+	cpuProfile    = flag.String("cpuprofile", "", "write a CPU profile of the client to this file")
+	memProfile    = flag.String("memprofile", "", "write a memory profile of the client to this file before exiting")
+
+	outPath      = flag.String("out", "", "file to write the output to instead of stdout, compressed if it ends in .gz")
+	colorMode    = flag.String("color", "auto", "color the output, one of: always, never, auto (only if stdout is a terminal)")
+	sockets      = flag.String("sockets", "", "comma separated list of agent sockets to run a read-only command against, instead of the default socket")
+	noHeaders    = flag.Bool("no-headers", false, "do not print the header rows of tables and CSV output")
+	tlsCA        = flag.String("tls-ca", "", "CA certificate file to verify agents reached over tcp:// with, enables TLS")
+	tlsCert      = flag.String("tls-cert", "", "client certificate file to authenticate to agents reached over tcp:// with, enables TLS")
+	tlsKey       = flag.String("tls-key", "", "key file of -tls-cert")
+	waitForAgent = flag.Duration("wait-for-agent", 0, "retry connecting to the agent for up to this duration before running the command, e.g. while it restarts")
+	contextName  = flag.String("context", "", "prefix every output line with [NAME], or add a context field to JSON output, to tell apart the output of several clusters")
+	showVersion  = flag.Bool("version", false, "print the client and agent versions and exit, same as the version command")
+)
+
 func main() {
-	// Connect to the default path /var/run/cilium/cilium.sock
-	c, err := client.NewDefaultClient()
-	if err != nil {
-		panic(err)
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := setupLogging(*logLevel, *logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging configuration: %s\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := setupColor(*colorMode, *outPath); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", err)
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	// List all endpoints
-	eps, err := c.EndpointList()
+	name, args := defaultCommand, flag.Args()
+	if *showVersion {
+		name, args = "version", nil
+	} else if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *sockets != "" && !cmd.readOnly {
+		fmt.Fprintf(os.Stderr, "command %q cannot be run with -sockets\n\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	stopProfiling, err := startProfiling(*cpuProfile, *memProfile)
 	if err != nil {
-		panic(err)
-	}
-
-	// Sort EPs per IDs
-	sort.Slice(eps, func(i, j int) bool {
-		return eps[i].ID < eps[j].ID
-	})
-
-	// Print the IPs of the endpoints
-	for _, ep := range eps {
-		var v4s, v6s []string
-		for _, ip := range ep.Status.Networking.Addressing {
-			if ip.IPV4 != "" {
-				v4s = append(v4s, ip.IPV4)
-			}
-			if ip.IPV4 != "" {
-				v6s = append(v6s, ip.IPV6)
-			}
-		}
-		ips := strings.Join(v4s, ", ")
-		if ips != "" {
-			fmt.Printf("EP ID %d has IP addresses: %s\n", ep.ID, ips)
+		log.WithError(err).Fatal("Unable to start profiling")
+	}
+	out, err := openOutput(*outPath)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to open output file")
+	}
+	if *contextName != "" {
+		out = newPrefixWriter(out, *contextName)
+	}
+	if *sockets != "" {
+		err = runOnSockets(cmd, args, strings.Split(*sockets, ","), out)
+	} else {
+		err = runOnSocket(cmd, args, "", out)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	stopProfiling()
+	if errors.Is(err, context.Canceled) {
+		// Interrupted by a signal, the output has been closed above.
+		return
+	}
+	if errors.Is(err, errUnhealthy) {
+		os.Exit(1)
+	}
+	if err != nil {
+		os.Exit(emitError(outputFormatArg(args), name, err))
+	}
+}
+
+// emitError reports the failure of command name on stderr and returns the
+// exit code to use. With -o json, the error is written as a JSON object so
+// that pipelines can parse failures the same way as the output.
+func emitError(format, name string, err error) int {
+	code := 1
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = exitTimeout
+	}
+	if format == "json" {
+		json.NewEncoder(os.Stderr).Encode(struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}{client.Hint(err).Error(), code})
+		return code
+	}
+	if code == exitTimeout {
+		if *timeoutPerCall > 0 {
+			log.WithError(err).Errorf("Command %s timed out after %s, or %s for a single call", name, *timeout, *timeoutPerCall)
 		} else {
-			fmt.Printf("EP ID %d does not have an IP address\n", ep.ID)
+			log.WithError(err).Errorf("Command %s timed out after %s", name, *timeout)
 		}
+	} else {
+		log.WithError(client.Hint(err)).Errorf("Command %s failed", name)
+	}
+	return code
+}
+
+// outputFormatArg returns the value of the -o flag in the command arguments,
+// if any. The flags of each command are only known to the command itself, so
+// args are scanned for the forms the flag package accepts for -o.
+func outputFormatArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == "o" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "o=") {
+			return strings.TrimPrefix(name, "o=")
+		}
+	}
+	return ""
+}
+
+// runOnSocket runs cmd against the agent listening on host.
+func runOnSocket(cmd *command, args []string, host string, out io.Writer) error {
+	opts, err := clientOptionsFromFlags(host)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(opts)
+	if err != nil {
+		return fmt.Errorf("unable to create Cilium API client: %w", err)
+	}
+
+	ctx, cancel := signalContext(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, signalContextKey{}, ctx)
+	if !cmd.longRunning {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	return cmd.run(ctx, c, out, args)
+}
+
+// signalContext returns a context canceled when the process receives SIGINT
+// or SIGTERM, so that commands, in particular long running ones, can stop
+// cleanly and let the output be flushed and closed.
+func signalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sigs)
+		select {
+		case <-sigs:
+			fmt.Fprintln(os.Stderr, "Shutting down")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// signalContextKey is the key under which runOnSocket stores the signal
+// context in the context passed to commands.
+type signalContextKey struct{}
+
+// renewTimeout returns a context with a fresh -timeout deadline, which is
+// still canceled on SIGINT or SIGTERM. Commands use it after waiting for the
+// user, e.g. to pick an endpoint, as that time does not count against
+// -timeout.
+func renewTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(withoutTimeout(ctx), *timeout)
+}
+
+// withoutTimeout returns ctx without the -timeout deadline, still canceled on
+// SIGINT or SIGTERM. Commands which are only long running with some of their
+// flags, e.g. -watch, use it rather than being registered as longRunning, and
+// must then apply -timeout to each of their API calls.
+func withoutTimeout(ctx context.Context) context.Context {
+	if sigCtx, ok := ctx.Value(signalContextKey{}).(context.Context); ok {
+		return sigCtx
+	}
+	return ctx
+}
+
+// callContext returns the context of a single API call made with ctx. If
+// -timeout-per-call is set, the call is bound by it in addition to the
+// deadline of ctx, so that one slow call does not use up the whole -timeout
+// of a command making several independent calls.
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if *timeoutPerCall <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, *timeoutPerCall)
 }
@@ -0,0 +1,194 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRenewTimeout(t *testing.T) {
+	sigCtx, stop := context.WithCancel(context.Background())
+	ctx := context.WithValue(sigCtx, signalContextKey{}, sigCtx)
+	expired, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+
+	renewed, cancelRenewed := renewTimeout(expired)
+	defer cancelRenewed()
+	if err := renewed.Err(); err != nil {
+		t.Fatalf("renewed context done right away: %v", err)
+	}
+	if deadline, ok := renewed.Deadline(); !ok || time.Until(deadline) <= 0 {
+		t.Errorf("renewed context has no fresh deadline: %v, %t", deadline, ok)
+	}
+
+	stop()
+	select {
+	case <-renewed.Done():
+	case <-time.After(time.Second):
+		t.Fatal("renewed context not canceled along with the signal context")
+	}
+}
+
+func TestOutputFormatArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"-o", "json"}, "json"},
+		{[]string{"--o=csv"}, "csv"},
+		{[]string{"-l", "k8s:app=web", "-o", "json", "-sort", "id"}, "json"},
+		{[]string{"-id", "12", "-o"}, ""},
+		{[]string{"-ids", "-out", "x"}, ""},
+		{[]string{"--", "-o", "json"}, ""},
+	}
+	for _, tt := range tests {
+		if got := outputFormatArg(tt.args); got != tt.want {
+			t.Errorf("outputFormatArg(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestEmitErrorJSON(t *testing.T) {
+	tests := []struct {
+		err  error
+		msg  string
+		code int
+	}{
+		{errors.New("unknown endpoint"), "unknown endpoint", 1},
+		{fmt.Errorf("get endpoints: %w", context.DeadlineExceeded), "Cilium API client timeout exceeded", exitTimeout},
+	}
+	for _, tt := range tests {
+		stderr := filepath.Join(t.TempDir(), "stderr")
+		f, err := os.Create(stderr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old := os.Stderr
+		os.Stderr = f
+		code := emitError("json", "endpoints", tt.err)
+		os.Stderr = old
+		f.Close()
+
+		if code != tt.code {
+			t.Errorf("emitError(%v) = %d, want %d", tt.err, code, tt.code)
+		}
+		data, err := os.ReadFile(stderr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("stderr is not JSON: %v\n%s", err, data)
+		}
+		if got.Error != tt.msg || got.Code != tt.code {
+			t.Errorf("emitError(%v) wrote %+v", tt.err, got)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	defer func(old time.Duration) { *timeout = old }(*timeout)
+	*timeout = 50 * time.Millisecond
+	logs := captureLogs(t)
+
+	agent, _ := newFakeAgent(t)
+	agent.handle("GET /endpoint", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	start := time.Now()
+	err := runOnSocket(commands["endpoints"], nil, agent.host, nopCloser{&bytes.Buffer{}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("runOnSocket() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command returned after %s, not bound by -timeout", elapsed)
+	}
+	if n := len(logs.withMessage("API call timed out")); n != 1 {
+		t.Errorf("%d timed out calls logged, want 1", n)
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	defer func(old time.Duration) { *timeoutPerCall = old }(*timeoutPerCall)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	*timeoutPerCall = 0
+	callCtx, cancelCall := callContext(ctx)
+	if got, _ := callCtx.Deadline(); !got.Equal(deadline) {
+		t.Errorf("call deadline = %s without -timeout-per-call, want the one of the command %s", got, deadline)
+	}
+	cancelCall()
+
+	*timeoutPerCall = time.Second
+	callCtx, cancelCall = callContext(ctx)
+	defer cancelCall()
+	if got, _ := callCtx.Deadline(); time.Until(got) > time.Second {
+		t.Errorf("call deadline = %s, not bound by -timeout-per-call", got)
+	}
+}
+
+func TestSignalContext(t *testing.T) {
+	// Keep the message printed on a signal out of the test output.
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() { os.Stderr = stderr }()
+
+	ctx, cancel := signalContext(context.Background())
+	defer cancel()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not canceled on SIGTERM")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("ctx.Err() = %v, want %v", ctx.Err(), context.Canceled)
+	}
+}
+
+func TestSignalContextParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := signalContext(parent)
+	defer cancel()
+	if ctx.Err() != nil {
+		t.Fatalf("ctx.Err() = %v before any signal", ctx.Err())
+	}
+	cancelParent()
+	<-ctx.Done()
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/defaults"
+)
+
+func init() {
+	register(&command{
+		name:     "nodes",
+		usage:    "list local and remote cluster nodes known to the agent",
+		run:      runNodes,
+		readOnly: true,
+	})
+}
+
+// clusterNode is a node as reported by the agent, split into its cluster and
+// host name.
+type clusterNode struct {
+	cluster string
+	name    string
+	remote  bool
+	ips     []string
+	health  string
+}
+
+func runNodes(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("nodes", flag.ExitOnError)
+	cluster := fs.String("cluster", "", "only list nodes of the given cluster")
+	fs.Parse(args)
+
+	resp, err := c.Daemon.GetClusterNodes(daemon.NewGetClusterNodesParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	// The status is only used to annotate nodes with the health of their
+	// cluster, so don't give up if it cannot be retrieved.
+	var status *models.StatusResponse
+	if sr, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx)); err == nil {
+		status = sr.Payload
+	}
+
+	nodes := filterNodes(clusterNodes(resp.Payload, status), *cluster)
+	printNodes(out, nodes)
+	return nil
+}
+
+// splitNodeName splits a node name of the form <cluster>/<node> into its
+// parts. Names without a cluster belong to the default cluster.
+func splitNodeName(name string) (cluster, node string) {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return defaults.ClusterName, name
+}
+
+// clusterNodes converts the agent's view of the cluster nodes into a list
+// sorted by cluster and then by node name.
+func clusterNodes(resp *models.ClusterNodeStatus, status *models.StatusResponse) []clusterNode {
+	if resp == nil {
+		return nil
+	}
+	localCluster, _ := splitNodeName(resp.Self)
+
+	nodes := make([]clusterNode, 0, len(resp.NodesAdded))
+	for _, n := range resp.NodesAdded {
+		cluster, name := splitNodeName(n.Name)
+		node := clusterNode{
+			cluster: cluster,
+			name:    name,
+			remote:  cluster != localCluster,
+			ips:     nodeIPs(n),
+		}
+		node.health = clusterHealth(status, node.cluster, node.remote)
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].cluster != nodes[j].cluster {
+			return nodes[i].cluster < nodes[j].cluster
+		}
+		return nodes[i].name < nodes[j].name
+	})
+	return nodes
+}
+
+// nodeIPs returns the primary IPv4 and IPv6 addresses of n followed by its
+// secondary addresses.
+func nodeIPs(n *models.NodeElement) []string {
+	var ips []string
+	if a := n.PrimaryAddress; a != nil {
+		if a.IPV4 != nil && a.IPV4.IP != "" {
+			ips = append(ips, a.IPV4.IP)
+		}
+		if a.IPV6 != nil && a.IPV6.IP != "" {
+			ips = append(ips, a.IPV6.IP)
+		}
+	}
+	for _, a := range n.SecondaryAddresses {
+		if a != nil && a.IP != "" {
+			ips = append(ips, a.IP)
+		}
+	}
+	return ips
+}
+
+// clusterHealth derives the health of a node from the status of the cluster
+// it belongs to. Remote clusters report their readiness through clustermesh
+// while the local cluster is covered by cilium-health.
+func clusterHealth(status *models.StatusResponse, cluster string, remote bool) string {
+	if status == nil {
+		return "unknown"
+	}
+	if !remote {
+		if status.Cluster == nil || status.Cluster.CiliumHealth == nil {
+			return "unknown"
+		}
+		return status.Cluster.CiliumHealth.State
+	}
+	if status.ClusterMesh == nil {
+		return "unknown"
+	}
+	for _, rc := range status.ClusterMesh.Clusters {
+		if rc.Name != cluster {
+			continue
+		}
+		if !rc.Ready {
+			return "not-ready"
+		}
+		return "ready"
+	}
+	return "unknown"
+}
+
+// filterNodes returns the nodes belonging to cluster, or all nodes if cluster
+// is empty.
+func filterNodes(nodes []clusterNode, cluster string) []clusterNode {
+	if cluster == "" {
+		return nodes
+	}
+	var filtered []clusterNode
+	for _, n := range nodes {
+		if n.cluster == cluster {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// printNodes writes nodes as a table. Nodes of remote clusters are marked
+// with an asterisk so they stand out from the local ones.
+func printNodes(out io.Writer, nodes []clusterNode) {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "CLUSTER\tNODE\tIP ADDRESSES\tHEALTH")
+	hasRemote := false
+	for _, n := range nodes {
+		cluster := n.cluster
+		if n.remote {
+			cluster = "*" + cluster
+			hasRemote = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cluster, n.name, strings.Join(n.ips, ", "), colorState(n.health))
+	}
+	w.Flush()
+	if hasRemote {
+		fmt.Fprintln(out, "\n* remote cluster")
+	}
+}
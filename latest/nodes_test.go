@@ -0,0 +1,143 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestSplitNodeName(t *testing.T) {
+	tests := []struct {
+		name, cluster, node string
+	}{
+		{"node-1", "default", "node-1"},
+		{"east/node-1", "east", "node-1"},
+		{"east/", "east", ""},
+	}
+	for _, tt := range tests {
+		cluster, node := splitNodeName(tt.name)
+		if cluster != tt.cluster || node != tt.node {
+			t.Errorf("splitNodeName(%q) = %q, %q, want %q, %q", tt.name, cluster, node, tt.cluster, tt.node)
+		}
+	}
+}
+
+func TestClusterHealth(t *testing.T) {
+	status := &models.StatusResponse{
+		Cluster: &models.ClusterStatus{CiliumHealth: &models.Status{State: models.StatusStateOk}},
+		ClusterMesh: &models.ClusterMeshStatus{Clusters: []*models.RemoteCluster{
+			{Name: "east", Ready: true},
+			{Name: "west"},
+		}},
+	}
+	tests := []struct {
+		name    string
+		status  *models.StatusResponse
+		cluster string
+		remote  bool
+		want    string
+	}{
+		{"no status", nil, "default", false, "unknown"},
+		{"local", status, "default", false, models.StatusStateOk},
+		{"local without health", &models.StatusResponse{}, "default", false, "unknown"},
+		{"remote ready", status, "east", true, "ready"},
+		{"remote not ready", status, "west", true, "not-ready"},
+		{"remote unknown", status, "north", true, "unknown"},
+		{"no cluster mesh", &models.StatusResponse{}, "east", true, "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterHealth(tt.status, tt.cluster, tt.remote); got != tt.want {
+				t.Errorf("clusterHealth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /cluster/nodes", http.StatusOK, &models.ClusterNodeStatus{
+		Self: "default/node-1",
+		NodesAdded: []*models.NodeElement{
+			{Name: "east/node-a", PrimaryAddress: &models.NodeAddressing{IPV4: &models.NodeAddressingElement{IP: "10.1.0.1"}}},
+			{
+				Name: "default/node-2",
+				PrimaryAddress: &models.NodeAddressing{
+					IPV4: &models.NodeAddressingElement{IP: "10.0.0.2"},
+					IPV6: &models.NodeAddressingElement{IP: "fd00::2"},
+				},
+				SecondaryAddresses: []*models.NodeAddressingElement{{IP: "192.168.0.2"}, nil},
+			},
+			{Name: "default/node-1"},
+		},
+	})
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Cluster:     &models.ClusterStatus{CiliumHealth: &models.Status{State: models.StatusStateOk}},
+		ClusterMesh: &models.ClusterMeshStatus{Clusters: []*models.RemoteCluster{{Name: "east"}}},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"all", nil, "" +
+			"CLUSTER   NODE     IP ADDRESSES                     HEALTH\n" +
+			"default   node-1                                    Ok\n" +
+			"default   node-2   10.0.0.2, fd00::2, 192.168.0.2   Ok\n" +
+			"*east     node-a   10.1.0.1                         not-ready\n" +
+			"\n* remote cluster\n"},
+		{"cluster", []string{"-cluster", "default"}, "" +
+			"CLUSTER   NODE     IP ADDRESSES                     HEALTH\n" +
+			"default   node-1                                    Ok\n" +
+			"default   node-2   10.0.0.2, fd00::2, 192.168.0.2   Ok\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := runNodes(context.Background(), c, &out, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runNodes() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNodesWithoutStatus(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /cluster/nodes", http.StatusOK, &models.ClusterNodeStatus{
+		Self:       "node-1",
+		NodesAdded: []*models.NodeElement{{Name: "node-1"}},
+	})
+	agent.respond("GET /healthz", http.StatusInternalServerError, nil)
+
+	var out bytes.Buffer
+	if err := runNodes(context.Background(), c, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "CLUSTER   NODE     IP ADDRESSES   HEALTH\ndefault   node-1                  unknown\n"
+	if out.String() != want {
+		t.Errorf("runNodes() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
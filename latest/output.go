@@ -0,0 +1,217 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// nopCloser is a writer whose Close does nothing. It is used for stdout,
+// which must stay open.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// gzipFile is a file written through a gzip writer. Closing it flushes the
+// compressed stream before closing the file.
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	err := g.Writer.Close()
+	if cerr := g.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// openOutput opens the destination for the output of a command. An empty
+// path or "-" stand for stdout. Otherwise, the file is created along with its
+// parent directories, or truncated if it exists, and gzip compressed if path
+// ends in ".gz". The returned writer must be closed to flush all output.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".gz") {
+		return gzipFile{gzip.NewWriter(f), f}, nil
+	}
+	return f, nil
+}
+
+// gzipReader is a file read through a gzip reader.
+type gzipReader struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipReader) Close() error {
+	err := g.Reader.Close()
+	if cerr := g.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// openInput opens a file written by openOutput, decompressing it if path ends
+// in ".gz".
+func openInput(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipReader{r, f}, nil
+}
+
+// writeJSON writes v as JSON, indented unless compact is set. Both forms
+// encode the same value, so they hold the same fields.
+func writeJSON(w io.Writer, v interface{}, compact bool) error {
+	enc := json.NewEncoder(w)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// printHeader writes the header row of a table, unless -no-headers is set.
+func printHeader(w io.Writer, header string) {
+	if *noHeaders {
+		return
+	}
+	fmt.Fprintln(w, header)
+}
+
+// terminalWidth returns the width of the terminal the output goes to, or 0 if
+// it is not written to a terminal.
+func terminalWidth() int {
+	if *outPath != "" && *outPath != "-" {
+		return 0
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// truncateList joins items with sep, truncated to at most max characters if
+// max is positive. Truncated lists end in "…" followed by the number of items
+// left out entirely, e.g. "k8s:app=web,k8s:te… +2 more".
+func truncateList(items []string, sep string, max int) string {
+	s := strings.Join(items, sep)
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	starts := make([]int, len(items))
+	for i, pos := 0, 0; i < len(items); i++ {
+		starts[i] = pos
+		pos += utf8.RuneCountInString(items[i]) + utf8.RuneCountInString(sep)
+	}
+	for cut := max - 1; cut > 0; cut-- {
+		hidden := 0
+		for _, start := range starts {
+			if start >= cut {
+				hidden++
+			}
+		}
+		suffix := "…"
+		if hidden > 0 {
+			suffix += fmt.Sprintf(" +%d more", hidden)
+		}
+		if cut+utf8.RuneCountInString(suffix) <= max {
+			return strings.TrimSuffix(string(runes[:cut]), sep) + suffix
+		}
+	}
+	// Not even the count of hidden items fits.
+	return string(runes[:max-1]) + "…"
+}
+
+// prefixWriter writes the prefix given with -context at the start of every
+// line.
+type prefixWriter struct {
+	io.WriteCloser
+	prefix  []byte
+	midLine bool
+}
+
+func newPrefixWriter(w io.WriteCloser, name string) *prefixWriter {
+	return &prefixWriter{WriteCloser: w, prefix: []byte("[" + name + "] ")}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 {
+		if !p.midLine {
+			if _, err := p.WriteCloser.Write(p.prefix); err != nil {
+				return n, err
+			}
+			p.midLine = true
+		}
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+			p.midLine = false
+		}
+		m, err := p.WriteCloser.Write(line)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[len(line):]
+	}
+	return n, nil
+}
+
+// rawOutput returns the writer underneath the -context prefix, if any, for
+// output formats such as JSON and CSV which a prefix would break. JSON output
+// carries the context in a field instead.
+func rawOutput(w io.Writer) io.Writer {
+	if p, ok := w.(*prefixWriter); ok {
+		return p.WriteCloser
+	}
+	return w
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestTruncateList(t *testing.T) {
+	items := []string{"k8s:app=web", "k8s:tier=frontend", "k8s:zone=a"}
+	tests := []struct {
+		max  int
+		want string
+	}{
+		{0, "k8s:app=web,k8s:tier=frontend,k8s:zone=a"},
+		{40, "k8s:app=web,k8s:tier=frontend,k8s:zone=a"},
+		{30, "k8s:app=web,k8s:tier=… +1 more"},
+		{20, "k8s:app=web… +2 more"},
+		{12, "k8s… +2 more"},
+		{5, "k8s:…"},
+		{1, "…"},
+	}
+	for _, tt := range tests {
+		got := truncateList(items, ",", tt.max)
+		if got != tt.want {
+			t.Errorf("truncateList(%d) = %q, want %q", tt.max, got, tt.want)
+		}
+		if n := utf8.RuneCountInString(got); tt.max > 0 && n > tt.max {
+			t.Errorf("truncateList(%d) = %q is %d characters long", tt.max, got, n)
+		}
+	}
+}
+
+func TestPrintEndpointFieldsMaxWidth(t *testing.T) {
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 0, "k8s:app=web", "k8s:tier=frontend", "k8s:zone=a"),
+	}
+	var buf bytes.Buffer
+	if err := printEndpointFields(&buf, eps, []string{"id", "labels", "state"}, 20); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"ID   LABELS                 STATE\n" +
+		"1    k8s:app=web… +2 more   ready\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printEndpointFields() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"single line", []string{"a\n"}, "[prod] a\n"},
+		{"several lines", []string{"a\nb\n"}, "[prod] a\n[prod] b\n"},
+		{"split line", []string{"a", "b\nc", "\n"}, "[prod] ab\n[prod] c\n"},
+		{"no trailing newline", []string{"a\nb"}, "[prod] a\n[prod] b"},
+		{"empty write", []string{""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newPrefixWriter(nopCloser{&buf}, "prod")
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+				}
+			}
+			if buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRawOutput(t *testing.T) {
+	var buf bytes.Buffer
+	w := newPrefixWriter(nopCloser{&buf}, "prod")
+	rawOutput(w).Write([]byte("{}\n"))
+	if buf.String() != "{}\n" {
+		t.Errorf("rawOutput() wrote %q, want no prefix", buf.String())
+	}
+	if got := rawOutput(&buf); got != &buf {
+		t.Errorf("rawOutput() = %v, want the writer as is", got)
+	}
+}
+
+func TestPrintHeader(t *testing.T) {
+	drops := []dropCount{{reason: "Policy denied", direction: "INGRESS", packets: 3}}
+	tests := []struct {
+		noHeaders bool
+		want      string
+	}{
+		{false, "RANK   REASON          DIRECTION   PACKETS\n1      Policy denied   INGRESS     3\n"},
+		// The columns are only as wide as the rows without the header.
+		{true, "1   Policy denied   INGRESS   3\n"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func(old bool) { *noHeaders = old }(*noHeaders)
+			*noHeaders = tt.noHeaders
+
+			var buf bytes.Buffer
+			printDrops(&buf, drops)
+			if buf.String() != tt.want {
+				t.Errorf("printDrops() with -no-headers=%v =\n%s\nwant\n%s", tt.noHeaders, buf.String(), tt.want)
+			}
+		}()
+	}
+}
+
+func TestOpenOutput(t *testing.T) {
+	for _, name := range []string{"out.txt", "out.txt.gz"} {
+		t.Run(name, func(t *testing.T) {
+			// Missing parent directories are created.
+			path := filepath.Join(t.TempDir(), "reports", name)
+			for _, content := range []string{"a longer first version\n", "second\n"} {
+				w, err := openOutput(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := io.WriteString(w, content); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r, err := openInput(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// An existing file is truncated.
+			if string(data) != "second\n" {
+				t.Errorf("read back %q, want %q", data, "second\n")
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gz := bytes.HasPrefix(raw, []byte{0x1f, 0x8b}); gz != (filepath.Ext(name) == ".gz") {
+				t.Errorf("file compressed = %v, want %v", gz, !gz)
+			}
+		})
+	}
+}
+
+func TestOpenOutputStdout(t *testing.T) {
+	for _, path := range []string{"", "-"} {
+		w, err := openOutput(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nc, ok := w.(nopCloser); !ok || nc.Writer != os.Stdout {
+			t.Errorf("openOutput(%q) = %#v, want stdout", path, w)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/pkg/client"
+
+	"golang.org/x/term"
+)
+
+// maxPickerEntries is the maximum number of matching endpoints shown while
+// the user types.
+const maxPickerEntries = 10
+
+var errPickerCancelled = errors.New("endpoint selection cancelled")
+
+// pickerEntry is an endpoint the user can pick.
+type pickerEntry struct {
+	id          string
+	description string
+}
+
+// pickEndpoint lets the user pick one of the local endpoints and returns its
+// ID. The prompt is written to stderr so that it does not mix with the
+// output of the command.
+func pickEndpoint(ctx context.Context, c *client.Client) (string, error) {
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	sortEndpoints(resp.Payload, endpointSortKeys["id"], false)
+	entries := make([]pickerEntry, 0, len(resp.Payload))
+	for _, ep := range resp.Payload {
+		id := strconv.FormatInt(ep.ID, 10)
+		entries = append(entries, pickerEntry{
+			id:          id,
+			description: fmt.Sprintf("%s  %s  %s", id, endpointContainerName(ep), endpointNamespace(ep)),
+		})
+	}
+	if len(entries) == 0 {
+		return "", errors.New("no endpoints to pick from")
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return pickNumeric(os.Stdin, os.Stderr, entries)
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+	return pickFiltered(os.Stdin, os.Stderr, entries)
+}
+
+// filterPickerEntries returns the entries whose description contains query,
+// ignoring case.
+func filterPickerEntries(entries []pickerEntry, query string) []pickerEntry {
+	query = strings.ToLower(query)
+	var matches []pickerEntry
+	for _, e := range entries {
+		if strings.Contains(strings.ToLower(e.description), query) {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// pickFiltered reads keystrokes from r, which must be a terminal in raw mode,
+// and narrows down the entries shown on w as the user types. Enter picks the
+// first matching entry, Ctrl-C or Ctrl-D cancel.
+func pickFiltered(r io.Reader, w io.Writer, entries []pickerEntry) (string, error) {
+	br := bufio.NewReader(r)
+	query := ""
+	drawn := 0
+	for {
+		matches := filterPickerEntries(entries, query)
+		shown := matches
+		if len(shown) > maxPickerEntries {
+			shown = shown[:maxPickerEntries]
+		}
+		// Move back to the first line drawn last time and redraw from there.
+		if drawn > 0 {
+			fmt.Fprintf(w, "\x1b[%dA", drawn)
+			drawn = 0
+		}
+		fmt.Fprint(w, "\r\x1b[J")
+		for i, e := range shown {
+			marker := " "
+			if i == 0 {
+				marker = ">"
+			}
+			fmt.Fprintf(w, "%s %s\r\n", marker, e.description)
+		}
+		if len(matches) > len(shown) {
+			fmt.Fprintf(w, "  ... %d more\r\n", len(matches)-len(shown))
+			drawn++
+		}
+		fmt.Fprintf(w, "Endpoint: %s", query)
+		drawn += len(shown)
+
+		b, err := br.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == 3 || b == 4: // Ctrl-C, Ctrl-D
+			fmt.Fprint(w, "\r\n")
+			return "", errPickerCancelled
+		case b == '\r' || b == '\n':
+			if len(matches) > 0 {
+				fmt.Fprint(w, "\r\n")
+				return matches[0].id, nil
+			}
+		case b == 127 || b == 8: // Backspace
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case b >= 32 && b < 127:
+			query += string(b)
+		}
+	}
+}
+
+// pickNumeric lists the entries on w and reads the ID of the endpoint to pick
+// from r. It is used when stdin is not a terminal.
+func pickNumeric(r io.Reader, w io.Writer, entries []pickerEntry) (string, error) {
+	for _, e := range entries {
+		fmt.Fprintln(w, e.description)
+	}
+	fmt.Fprint(w, "Endpoint ID: ")
+
+	line, err := readLine(r)
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errPickerCancelled
+	}
+	id := strings.TrimSpace(line)
+	for _, e := range entries {
+		if e.id == id {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no endpoint with ID %q", id)
+}
+
+// readLine reads from r up to and including the next newline. Unlike a
+// bufio.Reader, it does not read past the newline, so that the rest of the
+// input is left for later prompts, e.g. the confirmation of endpoint-delete.
+func readLine(r io.Reader) (string, error) {
+	var sb strings.Builder
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			sb.WriteByte(b[0])
+			if b[0] == '\n' {
+				return sb.String(), nil
+			}
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+	}
+}
+
+// pickEndpointID sets *id to the endpoint picked with pickEndpoint if it is
+// empty and interactive is set. The time spent picking does not count against
+// -timeout: the returned context replaces ctx, and must be canceled.
+func pickEndpointID(ctx context.Context, c *client.Client, id *string, interactive bool) (context.Context, context.CancelFunc, error) {
+	if *id != "" || !interactive {
+		return ctx, func() {}, nil
+	}
+	picked, err := pickEndpoint(ctx, c)
+	if err != nil {
+		return ctx, func() {}, err
+	}
+	*id = picked
+	ctx, cancel := renewTimeout(ctx)
+	return ctx, cancel, nil
+}
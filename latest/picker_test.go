@@ -0,0 +1,99 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var testPickerEntries = []pickerEntry{
+	{"1", "1  web  default"},
+	{"2", "2  db  default"},
+	{"3", "3  dns  kube-system"},
+}
+
+func TestPickFiltered(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"enter picks the first entry", "\r", "1", nil},
+		{"typing narrows the list", "db\r", "2", nil},
+		{"ignoring case", "KUBE\n", "3", nil},
+		{"backspace", "dbx\x7f\x7f\x7fns\r", "3", nil},
+		{"enter without match", "cache\r\x7f\x7f\x7f\x7f\x7fweb\r", "1", nil},
+		{"ctrl-c", "db\x03", "", errPickerCancelled},
+		{"ctrl-d", "\x04", "", errPickerCancelled},
+		{"end of input", "db", "", io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickFiltered(strings.NewReader(tt.input), io.Discard, testPickerEntries)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("pickFiltered(%q) = %q, %v, want %q, %v", tt.input, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPickFilteredDraw(t *testing.T) {
+	var out strings.Builder
+	if _, err := pickFiltered(strings.NewReader("kube\r"), &out, testPickerEntries); err != nil {
+		t.Fatal(err)
+	}
+	// The last frame only lists the entry matching the query.
+	frames := strings.Split(out.String(), "\r\x1b[J")
+	last := frames[len(frames)-1]
+	if want := "> 3  dns  kube-system\r\nEndpoint: kube\r\n"; last != want {
+		t.Errorf("last frame = %q, want %q", last, want)
+	}
+}
+
+func TestPickNumeric(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"2\n", "2", false},
+		{" 3 \n", "3", false},
+		{"3", "3", false},
+		{"42\n", "", true},
+		{"web\n", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := pickNumeric(strings.NewReader(tt.input), io.Discard, testPickerEntries)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("pickNumeric(%q) = %q, %v, want %q, error %t", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// The input after the ID is left for later prompts.
+	r := strings.NewReader("2\ny\n")
+	if _, err := pickNumeric(r, io.Discard, testPickerEntries); err != nil {
+		t.Fatal(err)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "y\n" {
+		t.Errorf("input left after pickNumeric() = %q, want %q", rest, "y\n")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "policy",
+		usage:    "print the policy revision and rule count, or every revision change with -watch",
+		run:      runPolicy,
+		readOnly: true,
+	})
+}
+
+// policyRevision is the revision of the policy repository of the agent and
+// the number of rules it holds.
+type policyRevision struct {
+	revision int64
+	rules    int
+}
+
+func runPolicy(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("policy", flag.ExitOnError)
+	watch := fs.Bool("watch", false, "keep polling the agent and print a line each time the revision increases")
+	interval := fs.Duration("interval", 2*time.Second, "interval at which the policy is fetched from the agent (with -watch)")
+	fs.Parse(args)
+
+	if *watch {
+		if *sockets != "" {
+			// The output of each agent is only printed once the command
+			// returns.
+			return errors.New("-watch cannot be used with -sockets")
+		}
+		ctx = withoutTimeout(ctx)
+	}
+
+	rev, err := pollPolicy(ctx, c)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Policy revision %d, %d rules\n", rev.revision, rev.rules)
+	if !*watch {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(*interval):
+		}
+		next, err := pollPolicy(ctx, c)
+		switch {
+		case err == nil:
+			if next.revision > rev.revision {
+				printPolicyRevisionChange(out, time.Now(), rev, next)
+				rev = next
+			}
+		case !isRetryable(err):
+			return err
+		default:
+			log.WithError(client.Hint(err)).Warning("Unable to get policy")
+		}
+	}
+}
+
+// pollPolicy returns the current policy revision. Each call is bound by
+// -timeout on its own as, with -watch, the command runs until interrupted.
+func pollPolicy(ctx context.Context, c *client.Client) (policyRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	resp, err := c.Policy.GetPolicy(policy.NewGetPolicyParamsWithContext(ctx))
+	if err != nil {
+		return policyRevision{}, err
+	}
+	// The rules are returned as a JSON array of rules.
+	var rules []json.RawMessage
+	if resp.Payload.Policy != "" {
+		if err := json.Unmarshal([]byte(resp.Payload.Policy), &rules); err != nil {
+			return policyRevision{}, fmt.Errorf("unable to parse policy rules: %w", err)
+		}
+	}
+	return policyRevision{revision: resp.Payload.Revision, rules: len(rules)}, nil
+}
+
+// printPolicyRevisionChange prints the change from old to new, with the time
+// it was noticed to correlate it with endpoint regenerations.
+func printPolicyRevisionChange(w io.Writer, now time.Time, old, new policyRevision) {
+	fmt.Fprintf(w, "%s: policy revision %d -> %d, %d rules (%+d)\n",
+		now.Format(time.RFC3339), old.revision, new.revision, new.rules, new.rules-old.rules)
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestPrintPolicyRevisionChange(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	printPolicyRevisionChange(&buf, now, policyRevision{revision: 3, rules: 5}, policyRevision{revision: 4, rules: 2})
+	if got, want := buf.String(), "2021-06-01T12:00:00Z: policy revision 3 -> 4, 2 rules (-3)\n"; got != want {
+		t.Errorf("printPolicyRevisionChange() = %q, want %q", got, want)
+	}
+}
+
+func TestPolicyWatchOutlivesTimeout(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	var revision int64
+	agent.handle("GET /policy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, &models.Policy{Revision: atomic.AddInt64(&revision, 1), Policy: "[{}]"}, true)
+	})
+
+	sigCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	ctx := context.WithValue(sigCtx, signalContextKey{}, sigCtx)
+	// The deadline of the command is already over, -watch must not be
+	// bound by it.
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	var buf bytes.Buffer
+	go func() {
+		for agent.callCount("GET /policy") < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		stop()
+	}()
+	err := runPolicy(ctx, c, &buf, []string{"-watch", "-interval", "1ms"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runPolicy() = %v, want %v", err, context.Canceled)
+	}
+	if !strings.HasPrefix(buf.String(), "Policy revision 1, 1 rules\n") || !strings.Contains(buf.String(), "policy revision 1 -> 2, 1 rules (+0)\n") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "policy-graph",
+		usage:    "print the policy selector cache as a Graphviz DOT graph (use -out graph.dot to save it)",
+		run:      runPolicyGraph,
+		readOnly: true,
+	})
+}
+
+func runPolicyGraph(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("policy-graph", flag.ExitOnError)
+	fs.Parse(args)
+
+	resp, err := c.Policy.GetPolicySelectors(policy.NewGetPolicySelectorsParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return writeSelectorGraph(out, filterSelectors(resp.Payload, 0))
+}
+
+// writeSelectorGraph writes a DOT graph with an edge from each selector to
+// each of the identities it selects. Identities selected by several
+// selectors are declared only once.
+func writeSelectorGraph(w io.Writer, mappings []*models.SelectorIdentityMapping) error {
+	fmt.Fprintln(w, "digraph selectors {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+
+	fmt.Fprintln(w, "  node [shape=box];")
+	for i, m := range mappings {
+		fmt.Fprintf(w, "  selector%d [label=%s];\n", i, strconv.Quote(m.Selector))
+	}
+
+	ids := make(map[int64]struct{})
+	for _, m := range mappings {
+		for _, id := range m.Identities {
+			ids[id] = struct{}{}
+		}
+	}
+	sortedIDs := make([]int64, 0, len(ids))
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Slice(sortedIDs, func(i, j int) bool {
+		return sortedIDs[i] < sortedIDs[j]
+	})
+	fmt.Fprintln(w, "  node [shape=ellipse];")
+	for _, id := range sortedIDs {
+		fmt.Fprintf(w, "  identity%d [label=\"%d\"];\n", id, id)
+	}
+
+	for i, m := range mappings {
+		for _, id := range m.Identities {
+			fmt.Fprintf(w, "  selector%d -> identity%d;\n", i, id)
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestWriteSelectorGraph(t *testing.T) {
+	mappings := []*models.SelectorIdentityMapping{
+		{Selector: `&LabelSelector{MatchLabels:map[string]string{any.app: "web",}}`, Identities: []int64{1001, 1000}},
+		{Selector: "&LabelSelector{reserved:host}", Identities: []int64{1, 1000}},
+		{Selector: "&LabelSelector{k8s:app=none}"},
+	}
+	var buf bytes.Buffer
+	if err := writeSelectorGraph(&buf, mappings); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph selectors {
+  rankdir=LR;
+  node [shape=box];
+  selector0 [label="&LabelSelector{MatchLabels:map[string]string{any.app: \"web\",}}"];
+  selector1 [label="&LabelSelector{reserved:host}"];
+  selector2 [label="&LabelSelector{k8s:app=none}"];
+  node [shape=ellipse];
+  identity1 [label="1"];
+  identity1000 [label="1000"];
+  identity1001 [label="1001"];
+  selector0 -> identity1001;
+  selector0 -> identity1000;
+  selector1 -> identity1;
+  selector1 -> identity1000;
+}
+`
+	if buf.String() != want {
+		t.Errorf("writeSelectorGraph() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "proxy-stats",
+		usage:    "show the L7 proxy request counts per protocol, of an endpoint or all of them (-id N)",
+		run:      runProxyStats,
+		readOnly: true,
+	})
+}
+
+// proxyCount is the number of requests handled by the L7 proxy for a
+// protocol, e.g. http, dns or kafka, in a direction.
+type proxyCount struct {
+	protocol  string
+	location  string
+	received  int64
+	forwarded int64
+	denied    int64
+	errors    int64
+}
+
+func runProxyStats(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("proxy-stats", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint, all endpoints if empty")
+	fs.Parse(args)
+
+	var eps []*models.Endpoint
+	if *id != "" {
+		ep, err := getEndpoint(ctx, c, *id)
+		if err != nil {
+			return err
+		}
+		eps = []*models.Endpoint{ep}
+	} else {
+		resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+		if err != nil {
+			return err
+		}
+		eps = resp.Payload
+	}
+	printProxyStats(out, aggregateProxyStats(eps))
+	return nil
+}
+
+// aggregateProxyStats sums the request counters of the proxy statistics of
+// eps by protocol and location, and returns them ranked by the number of
+// denied requests.
+func aggregateProxyStats(eps []*models.Endpoint) []proxyCount {
+	type key struct{ protocol, location string }
+	sums := make(map[key]*proxyCount)
+	for _, ep := range eps {
+		if ep.Status == nil || ep.Status.Policy == nil {
+			continue
+		}
+		for _, ps := range ep.Status.Policy.ProxyStatistics {
+			if ps == nil || ps.Statistics == nil || ps.Statistics.Requests == nil {
+				continue
+			}
+			k := key{ps.Protocol, ps.Location}
+			sum, ok := sums[k]
+			if !ok {
+				sum = &proxyCount{protocol: ps.Protocol, location: ps.Location}
+				sums[k] = sum
+			}
+			reqs := ps.Statistics.Requests
+			sum.received += reqs.Received
+			sum.forwarded += reqs.Forwarded
+			sum.denied += reqs.Denied
+			sum.errors += reqs.Error
+		}
+	}
+
+	counts := make([]proxyCount, 0, len(sums))
+	for _, sum := range sums {
+		counts = append(counts, *sum)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].denied != counts[j].denied {
+			return counts[i].denied > counts[j].denied
+		}
+		if counts[i].protocol != counts[j].protocol {
+			return counts[i].protocol < counts[j].protocol
+		}
+		return counts[i].location < counts[j].location
+	})
+	return counts
+}
+
+func printProxyStats(w io.Writer, counts []proxyCount) {
+	if len(counts) == 0 {
+		fmt.Fprintln(w, "No proxy statistics")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	printHeader(tw, "PROTOCOL\tLOCATION\tRECEIVED\tFORWARDED\tDENIED\tERROR")
+	for _, p := range counts {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", p.protocol, p.location, p.received, p.forwarded, p.denied, p.errors)
+	}
+	tw.Flush()
+}
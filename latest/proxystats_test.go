@@ -0,0 +1,84 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+// withProxyStats adds the request counters of a proxy redirect to ep.
+func withProxyStats(ep *models.Endpoint, protocol, location string, received, forwarded, denied, errors int64) *models.Endpoint {
+	if ep.Status.Policy == nil {
+		ep.Status.Policy = &models.EndpointPolicyStatus{}
+	}
+	ep.Status.Policy.ProxyStatistics = append(ep.Status.Policy.ProxyStatistics, &models.ProxyStatistics{
+		Protocol: protocol,
+		Location: location,
+		Statistics: &models.RequestResponseStatistics{
+			Requests: &models.MessageForwardingStatistics{Received: received, Forwarded: forwarded, Denied: denied, Error: errors},
+		},
+	})
+	return ep
+}
+
+func TestAggregateProxyStats(t *testing.T) {
+	ep1 := withProxyStats(testEndpoint(1, models.EndpointStateReady, 0), "http", "ingress", 10, 8, 2, 0)
+	withProxyStats(ep1, "dns", "egress", 100, 100, 0, 1)
+	ep2 := withProxyStats(testEndpoint(2, models.EndpointStateReady, 0), "http", "ingress", 5, 2, 3, 0)
+	withProxyStats(ep2, "http", "egress", 7, 7, 0, 0)
+	ep2.Status.Policy.ProxyStatistics = append(ep2.Status.Policy.ProxyStatistics, &models.ProxyStatistics{Protocol: "kafka"})
+	eps := []*models.Endpoint{ep1, ep2, testEndpoint(3, models.EndpointStateReady, 0)}
+
+	want := []proxyCount{
+		{protocol: "http", location: "ingress", received: 15, forwarded: 10, denied: 5},
+		{protocol: "dns", location: "egress", received: 100, forwarded: 100, errors: 1},
+		{protocol: "http", location: "egress", received: 7, forwarded: 7},
+	}
+	if got := aggregateProxyStats(eps); !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregateProxyStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrintProxyStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []proxyCount
+		want   string
+	}{
+		{"none", nil, "No proxy statistics\n"},
+		{"some", []proxyCount{
+			{protocol: "http", location: "ingress", received: 15, forwarded: 10, denied: 5},
+			{protocol: "dns", location: "egress", received: 100, forwarded: 100, errors: 1},
+		}, "" +
+			"PROTOCOL   LOCATION   RECEIVED   FORWARDED   DENIED   ERROR\n" +
+			"http       ingress    15         10          5        0\n" +
+			"dns        egress     100        100         0        1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printProxyStats(&buf, tt.counts)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("printProxyStats() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:  "regenerate",
+		usage: "trigger the regeneration of an endpoint, or all endpoints (-id N | -all | -interactive [-wait] [-concurrency N])",
+		run:   runRegenerate,
+	})
+}
+
+func runRegenerate(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("regenerate", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint to regenerate")
+	interactive := fs.Bool("interactive", false, "pick the endpoint from a list if neither -id nor -all is given")
+	all := fs.Bool("all", false, "regenerate all endpoints")
+	wait := fs.Bool("wait", false, "wait for the endpoints to be ready again")
+	concurrency := fs.Int("concurrency", 4, "number of endpoints regenerated at the same time with -all")
+	fs.Parse(args)
+
+	if *id != "" && *all {
+		return errors.New("use either -id or -all")
+	}
+	ctx, cancel, err := pickEndpointID(ctx, c, id, *interactive && !*all)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if (*id == "") == !*all {
+		return errors.New("use either -id, -all or -interactive")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d, must be at least 1", *concurrency)
+	}
+
+	ids := []string{*id}
+	if *all {
+		resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+		if err != nil {
+			return err
+		}
+		sortEndpoints(resp.Payload, endpointSortKeys["id"], false)
+		ids = ids[:0]
+		for _, ep := range resp.Payload {
+			ids = append(ids, strconv.FormatInt(ep.ID, 10))
+		}
+	}
+
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = regenerateEndpoint(ctx, c, ids[i], *wait)
+		}(i)
+	}
+	wg.Wait()
+
+	done := "regeneration triggered"
+	if *wait {
+		done = "regenerated"
+	}
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(out, "Endpoint %s: failed: %s\n", ids[i], client.Hint(err))
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "Endpoint %s: %s\n", ids[i], done)
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to regenerate %d of %d endpoints", failed, len(ids))
+	}
+	return nil
+}
+
+// regenerateEndpoint triggers the regeneration of the endpoint with the given
+// ID by moving it to the waiting-to-regenerate state, and optionally waits
+// for it to be ready again.
+func regenerateEndpoint(ctx context.Context, c *client.Client, id string, wait bool) error {
+	callCtx, cancel := callContext(ctx)
+	defer cancel()
+	params := endpoint.NewPatchEndpointIDParamsWithContext(callCtx).
+		WithID(id).
+		WithEndpoint(&models.EndpointChangeRequest{State: models.EndpointStateWaitingToRegenerate})
+	if _, err := c.Endpoint.PatchEndpointID(params); err != nil {
+		return err
+	}
+	if !wait {
+		return nil
+	}
+	ready := map[models.EndpointState]struct{}{models.EndpointStateReady: {}}
+	_, err := waitForEndpoints(ctx, c, io.Discard, id, ready)
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestRegenerateArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{nil, "use either -id, -all or -interactive"},
+		{[]string{"-id", "1", "-all"}, "use either -id or -all"},
+		{[]string{"-all", "-concurrency", "0"}, "invalid concurrency 0, must be at least 1"},
+	}
+	for _, tt := range tests {
+		err := runRegenerate(context.Background(), nil, &bytes.Buffer{}, tt.args)
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("runRegenerate(%q) error = %v, want %s", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegenerateAll(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		testEndpoint(3, models.EndpointStateReady, 100),
+		testEndpoint(1, models.EndpointStateReady, 100),
+		testEndpoint(4, models.EndpointStateReady, 100),
+		testEndpoint(2, models.EndpointStateReady, 100),
+		testEndpoint(5, models.EndpointStateReady, 100),
+	})
+	var (
+		mu                  sync.Mutex
+		inFlight, maxFlight int
+	)
+	patch := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if inFlight++; inFlight > maxFlight {
+			maxFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}
+	for _, id := range []string{"1", "2", "3", "5"} {
+		agent.handle("PATCH /endpoint/"+id, patch)
+	}
+	agent.respond("PATCH /endpoint/4", http.StatusNotFound, nil)
+
+	var out bytes.Buffer
+	err := runRegenerate(context.Background(), c, &out, []string{"-all", "-concurrency", "2"})
+	if want := "unable to regenerate 1 of 5 endpoints"; err == nil || err.Error() != want {
+		t.Errorf("runRegenerate() error = %v, want %s", err, want)
+	}
+	want := "" +
+		"Endpoint 1: regeneration triggered\n" +
+		"Endpoint 2: regeneration triggered\n" +
+		"Endpoint 3: regeneration triggered\n" +
+		"Endpoint 4: failed: [PATCH /endpoint/{id}][404] patchEndpointIdNotFound \n" +
+		"Endpoint 5: regeneration triggered\n"
+	if out.String() != want {
+		t.Errorf("runRegenerate() =\n%s\nwant\n%s", out.String(), want)
+	}
+	if maxFlight > 2 {
+		t.Errorf("%d endpoints were regenerated at the same time, want at most 2", maxFlight)
+	}
+}
+
+func TestRegenerateWait(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("PATCH /endpoint/1", http.StatusOK, nil)
+	agent.respond("GET /endpoint/1", http.StatusOK, testEndpoint(1, models.EndpointStateReady, 100))
+
+	var out bytes.Buffer
+	if err := runRegenerate(context.Background(), c, &out, []string{"-id", "1", "-wait"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Endpoint 1: regenerated\n"; out.String() != want {
+		t.Errorf("runRegenerate() = %q, want %q", out.String(), want)
+	}
+	if n := agent.callCount("GET /endpoint/1"); n != 1 {
+		t.Errorf("endpoint was polled %d times, want 1", n)
+	}
+}
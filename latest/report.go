@@ -0,0 +1,161 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "report",
+		usage:    "print the agent status, the local endpoints and the identities (-best-effort)",
+		run:      runReport,
+		readOnly: true,
+	})
+}
+
+// reportSection is a part of the report. Each section makes its own API
+// calls so that it can fail independently of the others.
+type reportSection struct {
+	name string
+	run  func(ctx context.Context, c *client.Client, w io.Writer) error
+}
+
+var reportSections = []reportSection{
+	{name: "Status", run: reportStatus},
+	{name: "Endpoints", run: reportEndpoints},
+	{name: "Identities", run: reportIdentities},
+}
+
+func runReport(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	bestEffort := fs.Bool("best-effort", false, "print the sections which succeed and summarize the failed ones, instead of stopping at the first failure")
+	fs.Parse(args)
+
+	return runSections(ctx, c, out, reportSections, *bestEffort)
+}
+
+// runSections runs sections in order and writes the output of each one which
+// succeeds. Unless bestEffort is set, the first failure is returned right
+// away. Otherwise the failures are listed after the last section, and an
+// error is returned only if all sections failed.
+func runSections(ctx context.Context, c *client.Client, out io.Writer, sections []reportSection, bestEffort bool) error {
+	type failure struct {
+		section string
+		err     error
+	}
+	var failures []failure
+
+	for _, s := range sections {
+		// Buffer the section so that a failure does not leave partial
+		// output behind.
+		var buf bytes.Buffer
+		callCtx, cancel := callContext(ctx)
+		err := s.run(callCtx, c, &buf)
+		cancel()
+		if err != nil {
+			if !bestEffort {
+				return fmt.Errorf("section %s: %w", s.name, err)
+			}
+			log.WithError(err).Debugf("Section %s failed", s.name)
+			failures = append(failures, failure{s.name, err})
+			continue
+		}
+		fmt.Fprintf(out, "%s:\n", s.name)
+		buf.WriteTo(out)
+		fmt.Fprintln(out)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "%d of %d sections failed:\n", len(failures), len(sections))
+	for _, f := range failures {
+		fmt.Fprintf(out, "  %s: %s\n", f.section, client.Hint(f.err))
+	}
+	if len(failures) == len(sections) {
+		return fmt.Errorf("all %d sections failed", len(sections))
+	}
+	return nil
+}
+
+func reportStatus(ctx context.Context, c *client.Client, w io.Writer) error {
+	resp, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return printStatusComponents(w, resp.Payload)
+}
+
+// printStatusComponents prints the state of the agent and of the components
+// it depends on.
+func printStatusComponents(w io.Writer, sr *models.StatusResponse) error {
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	for _, s := range []struct {
+		name   string
+		status *models.Status
+	}{
+		{"Cilium", sr.Cilium},
+		{"Kvstore", sr.Kvstore},
+		{"ContainerRuntime", sr.ContainerRuntime},
+	} {
+		if s.status == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.name, colorState(s.status.State), s.status.Msg)
+	}
+	return tw.Flush()
+}
+
+func reportEndpoints(ctx context.Context, c *client.Client, w io.Writer) error {
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	sortEndpoints(resp.Payload, endpointSortKeys["id"], false)
+	return printEndpointsStreaming(w, resp.Payload, true)
+}
+
+func reportIdentities(ctx context.Context, c *client.Client, w io.Writer) error {
+	resp, err := c.Policy.GetIdentity(policy.NewGetIdentityParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	ids := resp.Payload
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].ID < ids[j].ID
+	})
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	for _, id := range ids {
+		fmt.Fprintf(tw, "  %d\t%s\n", id.ID, labels.NewLabelsFromModel(id.Labels))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// testSection returns a report section which writes its name, or fails with
+// err after writing partial output if err is not nil.
+func testSection(name string, err error) reportSection {
+	return reportSection{name: name, run: func(_ context.Context, _ *client.Client, w io.Writer) error {
+		if err != nil {
+			fmt.Fprintln(w, "  partial")
+			return err
+		}
+		fmt.Fprintf(w, "  %s output\n", name)
+		return nil
+	}}
+}
+
+func TestRunSections(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name       string
+		sections   []reportSection
+		bestEffort bool
+		want       string
+		wantErr    string
+	}{
+		{"all succeed", []reportSection{testSection("A", nil), testSection("B", nil)}, false,
+			"A:\n  A output\n\nB:\n  B output\n\n", ""},
+		{"stop at first failure", []reportSection{testSection("A", nil), testSection("B", errBoom), testSection("C", nil)}, false,
+			"A:\n  A output\n\n", "section B: boom"},
+		{"best effort", []reportSection{testSection("A", errBoom), testSection("B", nil), testSection("C", errBoom)}, true,
+			"B:\n  B output\n\n2 of 3 sections failed:\n  A: boom\n  C: boom\n", ""},
+		{"all fail", []reportSection{testSection("A", errBoom), testSection("B", errBoom)}, true,
+			"2 of 2 sections failed:\n  A: boom\n  B: boom\n", "all 2 sections failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runSections(context.Background(), nil, &out, tt.sections, tt.bestEffort)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("runSections() error = %v, want %q", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runSections() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "resolve-labels",
+		usage:    "look up the identity of a set of labels (-l k8s:app=foo,k8s:env=prod)",
+		run:      runResolveLabels,
+		readOnly: true,
+	})
+}
+
+func runResolveLabels(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("resolve-labels", flag.ExitOnError)
+	lblsArg := fs.String("l", "", "comma separated list of labels in the form SOURCE:KEY=VALUE, or $KEY for reserved labels, e.g. $host")
+	fs.Parse(args)
+
+	if *lblsArg == "" {
+		return errors.New("missing labels, use -l")
+	}
+	lbls := canonicalizeLabels(labels.NewLabelsFromModel(strings.Split(*lblsArg, ",")))
+	warnUnknownSources(lbls)
+
+	id, err := lookupIdentity(ctx, c, lbls)
+	if err != nil {
+		return err
+	}
+	printIdentity(out, lbls, id)
+	return nil
+}
+
+// lookupIdentity returns the identity allocated for exactly the labels
+// lbls, or nil if there is none yet.
+func lookupIdentity(ctx context.Context, c *client.Client, lbls labels.Labels) (*models.Identity, error) {
+	resp, err := c.Policy.GetIdentity(policy.NewGetIdentityParamsWithContext(ctx).WithLabels(lbls.GetModel()))
+	var notFound *policy.GetIdentityNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	for _, id := range resp.Payload {
+		if id != nil {
+			return id, nil
+		}
+	}
+	return nil, nil
+}
+
+func printIdentity(w io.Writer, lbls labels.Labels, id *models.Identity) {
+	if id == nil {
+		fmt.Fprintf(w, "No identity allocated for labels %s\n", lbls)
+		return
+	}
+	if !isReservedIdentity(id.ID) {
+		fmt.Fprintf(w, "Labels %s map to allocated identity %d\n", lbls, id.ID)
+		return
+	}
+	name := reservedIdentityName(labels.NewLabelsFromModel(id.Labels))
+	if name == "" {
+		name = "unknown"
+	}
+	fmt.Fprintf(w, "Labels %s map to reserved identity %d (%s)\n", lbls, id.ID, name)
+}
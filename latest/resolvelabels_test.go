@@ -0,0 +1,76 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestIsReservedIdentity(t *testing.T) {
+	tests := []struct {
+		id   int64
+		want bool
+	}{
+		{0, false},
+		{1, true},
+		{255, true},
+		{256, false},
+		{16777217, false},
+	}
+	for _, tt := range tests {
+		if got := isReservedIdentity(tt.id); got != tt.want {
+			t.Errorf("isReservedIdentity(%d) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   interface{}
+		want   string
+	}{
+		{"allocated", http.StatusOK, []*models.Identity{{ID: 1000, Labels: models.Labels{"k8s:app=web"}}},
+			"Labels k8s:app=web map to allocated identity 1000\n"},
+		{"reserved", http.StatusOK, []*models.Identity{{ID: 1, Labels: models.Labels{"reserved:host"}}},
+			"Labels k8s:app=web map to reserved identity 1 (host)\n"},
+		{"empty", http.StatusOK, []*models.Identity{nil},
+			"No identity allocated for labels k8s:app=web\n"},
+		{"not found", http.StatusNotFound, nil,
+			"No identity allocated for labels k8s:app=web\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, c := newFakeAgent(t)
+			agent.respond("GET /identity", tt.status, tt.body)
+
+			var out bytes.Buffer
+			if err := runResolveLabels(context.Background(), c, &out, []string{"-l", "k8s:app=web"}); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runResolveLabels() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/go-openapi/runtime"
+)
+
+// apiStatusRe matches the errors returned by the generated client for the
+// documented responses of an operation, which are formatted as
+// "[METHOD PATH][CODE] operationName ...".
+var apiStatusRe = regexp.MustCompile(`^\[[A-Z]+ [^\]]*\]\[(\d{3})\]`)
+
+// apiStatusCode returns the HTTP status code of an error returned by the
+// generated client, or 0 if the agent did not answer.
+func apiStatusCode(err error) int {
+	var apiErr *runtime.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	if m := apiStatusRe.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code
+	}
+	return 0
+}
+
+// isRetryable returns true if err is transient, i.e. the same API call may
+// succeed later: the agent could not be reached, the call timed out, or the
+// agent answered with a server error or asked to slow down. Client errors,
+// such as an unknown endpoint or an invalid request, are permanent.
+func isRetryable(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	}
+	if code := apiStatusCode(err); code != 0 {
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+	}
+	// Failing to dial the socket, e.g. while the agent restarts.
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
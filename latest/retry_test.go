@@ -0,0 +1,88 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+
+	"github.com/go-openapi/runtime"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+		want bool
+	}{
+		{"nil", nil, 0, false},
+		{"canceled", context.Canceled, 0, false},
+		{"deadline", fmt.Errorf("get endpoints: %w", context.DeadlineExceeded), 0, true},
+		{"not found", endpoint.NewGetEndpointIDNotFound(), http.StatusNotFound, false},
+		{"invalid", endpoint.NewGetEndpointIDInvalid(), http.StatusBadRequest, false},
+		{"unavailable", runtime.NewAPIError("GetEndpoint", nil, http.StatusServiceUnavailable), http.StatusServiceUnavailable, true},
+		{"too many requests", runtime.NewAPIError("GetEndpoint", nil, http.StatusTooManyRequests), http.StatusTooManyRequests, true},
+		{"dial", &net.OpError{Op: "dial", Net: "unix", Err: errors.New("connection refused")}, 0, true},
+		{"other", errors.New("unable to parse response"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+			if tt.err == nil {
+				return
+			}
+			if got := apiStatusCode(tt.err); got != tt.code {
+				t.Errorf("apiStatusCode(%v) = %d, want %d", tt.err, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestIsRetryableFromAgent(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusNotFound, false},
+		{http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			agent, c := newFakeAgent(t)
+			agent.respond("GET /endpoint/1", tt.status, nil)
+			_, err := c.Endpoint.GetEndpointID(endpoint.NewGetEndpointIDParamsWithContext(context.Background()).WithID("1"))
+			if err == nil {
+				t.Fatal("GetEndpointID() succeeded")
+			}
+			if got := apiStatusCode(err); got != tt.status {
+				t.Errorf("apiStatusCode(%v) = %d, want %d", err, got, tt.status)
+			}
+			if got := isRetryable(err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"strings"
+
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+// selectOp is the operator of a label selector.
+type selectOp int
+
+const (
+	// opEquals selects labels with the given key and value.
+	opEquals selectOp = iota
+	// opNotEquals selects label sets without the given key and value.
+	opNotEquals
+	// opExists selects label sets with the given key, regardless of value.
+	opExists
+	// opNotExists selects label sets without the given key.
+	opNotExists
+)
+
+// parseSelectLabelWithOp parses a label selector in one of the forms
+// "key=value", "key!=value", "key" or "!key". As with labels.ParseSelectLabel,
+// the key may be prefixed by a source and the source defaults to any.
+func parseSelectLabelWithOp(str string) (labels.Label, selectOp) {
+	if strings.HasPrefix(str, "!") {
+		return labels.ParseSelectLabel(str[1:]), opNotExists
+	}
+	if i := strings.Index(str, "!="); i >= 0 {
+		lbl := labels.ParseSelectLabel(str[:i])
+		lbl.Value = str[i+2:]
+		return lbl, opNotEquals
+	}
+	if !strings.Contains(str, "=") {
+		return labels.ParseSelectLabel(str), opExists
+	}
+	return labels.ParseSelectLabel(str), opEquals
+}
+
+// labelSelector is a label along with the operator selecting it.
+type labelSelector struct {
+	label labels.Label
+	op    selectOp
+}
+
+// parseLabelSelectors parses a comma separated list of label selectors.
+func parseLabelSelectors(str string) []labelSelector {
+	var sels []labelSelector
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		lbl, op := parseSelectLabelWithOp(s)
+		sels = append(sels, labelSelector{label: lbl, op: op})
+	}
+	return sels
+}
+
+// matches returns true if lbls are selected by s.
+func (s labelSelector) matches(lbls labels.Labels) bool {
+	lbl, found := lbls[s.label.Key]
+	if found && !s.label.IsAnySource() && s.label.Source != lbl.Source {
+		found = false
+	}
+
+	switch s.op {
+	case opExists:
+		return found
+	case opNotExists:
+		return !found
+	case opNotEquals:
+		return !labelsContain(lbls, labels.LabelArray{s.label})
+	default:
+		return labelsContain(lbls, labels.LabelArray{s.label})
+	}
+}
+
+// matchesAll returns true if lbls are selected by all of sels.
+func matchesAll(sels []labelSelector, lbls labels.Labels) bool {
+	for _, s := range sels {
+		if !s.matches(lbls) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelExpr is a boolean expression over label selectors. It is a list of
+// groups, and label sets are selected if they match all of the selectors of
+// any of the groups.
+type labelExpr [][]labelSelector
+
+// parseLabelExpr parses a label expression. Groups are separated by "|" and
+// the selectors within a group by ",", which binds tighter: "a|b,c" selects
+// label sets matching a, or both b and c. There is no way to group
+// alternatives with parentheses.
+func parseLabelExpr(str string) labelExpr {
+	var expr labelExpr
+	for _, group := range strings.Split(str, "|") {
+		if sels := parseLabelSelectors(group); len(sels) > 0 {
+			expr = append(expr, sels)
+		}
+	}
+	return expr
+}
+
+// matches returns true if lbls are selected by all selectors of any group
+// of e. An empty expression selects all label sets.
+func (e labelExpr) matches(lbls labels.Labels) bool {
+	if len(e) == 0 {
+		return true
+	}
+	for _, sels := range e {
+		if matchesAll(sels, lbls) {
+			return true
+		}
+	}
+	return false
+}
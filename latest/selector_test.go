@@ -0,0 +1,108 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func TestParseSelectLabelWithOp(t *testing.T) {
+	tests := []struct {
+		str  string
+		want labels.Label
+		op   selectOp
+	}{
+		{"app=web", labels.Label{Key: "app", Value: "web", Source: labels.LabelSourceAny}, opEquals},
+		{"k8s:app=web", labels.Label{Key: "app", Value: "web", Source: labels.LabelSourceK8s}, opEquals},
+		{"k8s:app!=web", labels.Label{Key: "app", Value: "web", Source: labels.LabelSourceK8s}, opNotEquals},
+		{"app", labels.Label{Key: "app", Source: labels.LabelSourceAny}, opExists},
+		{"!k8s:app", labels.Label{Key: "app", Source: labels.LabelSourceK8s}, opNotExists},
+	}
+	for _, tt := range tests {
+		lbl, op := parseSelectLabelWithOp(tt.str)
+		if lbl != tt.want || op != tt.op {
+			t.Errorf("parseSelectLabelWithOp(%q) = %v, %d, want %v, %d", tt.str, lbl, op, tt.want, tt.op)
+		}
+	}
+}
+
+func TestLabelSelectorsMatch(t *testing.T) {
+	lbls := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend", "container:debug"})
+	tests := []struct {
+		selectors string
+		want      bool
+	}{
+		{"", true},
+		{"app=web", true},
+		{"k8s:app=web", true},
+		{"container:app=web", false},
+		{"app=db", false},
+		{"app!=db", true},
+		{"app!=web", false},
+		{"k8s:app!=web", false},
+		{"container:app!=web", true},
+		{"tier", true},
+		{"owner", false},
+		{"container:tier", false},
+		{"!owner", true},
+		{"!tier", false},
+		{"!container:tier", true},
+		{"app=web, tier=frontend, !owner", true},
+		{"app=web,tier=backend", false},
+	}
+	for _, tt := range tests {
+		if got := matchesAll(parseLabelSelectors(tt.selectors), lbls); got != tt.want {
+			t.Errorf("selectors %q match = %t, want %t", tt.selectors, got, tt.want)
+		}
+	}
+}
+
+func TestLabelExprMatch(t *testing.T) {
+	web := labels.NewLabelsFromModel([]string{"k8s:app=web", "k8s:tier=frontend"})
+	db := labels.NewLabelsFromModel([]string{"k8s:app=db", "k8s:tier=backend"})
+	tests := []struct {
+		expr    string
+		groups  int
+		web, db bool
+	}{
+		{"", 0, true, true},
+		{"|", 0, true, true},
+		{"app=web", 1, true, false},
+		{"app=web|app=db", 2, true, true},
+		{"app=cache|app=db", 2, false, true},
+		// "," binds tighter than "|".
+		{"app=web|app=db,tier=frontend", 2, true, false},
+		{"app=web,tier=backend|app=db,tier=backend", 2, false, true},
+		{"app=web,tier=backend|app=db,tier=frontend", 2, false, false},
+		{"!tier|app=web", 2, true, false},
+		{" app=web | ", 1, true, false},
+	}
+	for _, tt := range tests {
+		expr := parseLabelExpr(tt.expr)
+		if len(expr) != tt.groups {
+			t.Errorf("parseLabelExpr(%q) has %d groups, want %d", tt.expr, len(expr), tt.groups)
+		}
+		if got := expr.matches(web); got != tt.web {
+			t.Errorf("%q matches web = %t, want %t", tt.expr, got, tt.web)
+		}
+		if got := expr.matches(db); got != tt.db {
+			t.Errorf("%q matches db = %t, want %t", tt.expr, got, tt.db)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/labels"
+)
+
+func init() {
+	register(&command{
+		name:     "selectors",
+		usage:    "list the selectors in the policy selector cache",
+		run:      runSelectors,
+		readOnly: true,
+	})
+}
+
+func runSelectors(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("selectors", flag.ExitOnError)
+	minUsers := fs.Int64("min-users", 0, "only list selectors with at least this many users")
+	resolve := fs.Bool("resolve", false, "list the labels of the selected identities")
+	fs.Parse(args)
+
+	resp, err := c.Policy.GetPolicySelectors(policy.NewGetPolicySelectorsParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	mappings := filterSelectors(resp.Payload, *minUsers)
+
+	if !*resolve {
+		printSelectors(out, mappings)
+		return nil
+	}
+	identities, err := resolveSelectorIdentities(ctx, newIdentityResolver(c), mappings)
+	if err != nil {
+		return err
+	}
+	printResolvedSelectors(out, mappings, identities)
+	return nil
+}
+
+// filterSelectors returns the mappings of selectors with at least minUsers
+// users, sorted by selector.
+func filterSelectors(cache models.SelectorCache, minUsers int64) []*models.SelectorIdentityMapping {
+	var mappings []*models.SelectorIdentityMapping
+	for _, m := range cache {
+		if m != nil && m.Users >= minUsers {
+			mappings = append(mappings, m)
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Selector < mappings[j].Selector
+	})
+	return mappings
+}
+
+// resolveSelectorIdentities looks up the labels of all identities selected by
+// mappings. Identities which no longer exist map to nil labels.
+func resolveSelectorIdentities(ctx context.Context, r *identityResolver, mappings []*models.SelectorIdentityMapping) (map[int64]labels.Labels, error) {
+	identities := make(map[int64]labels.Labels)
+	for _, m := range mappings {
+		for _, id := range m.Identities {
+			lbls, err := r.resolve(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve identity %d: %w", id, err)
+			}
+			identities[id] = lbls
+		}
+	}
+	return identities, nil
+}
+
+func printSelectors(out io.Writer, mappings []*models.SelectorIdentityMapping) {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "SELECTOR\tIDENTITIES\tUSERS")
+	for _, m := range mappings {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", m.Selector, len(m.Identities), m.Users)
+	}
+	w.Flush()
+}
+
+// printResolvedSelectors prints each selector followed by the selected
+// identities and their labels, one per line.
+func printResolvedSelectors(out io.Writer, mappings []*models.SelectorIdentityMapping, identities map[int64]labels.Labels) {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "SELECTOR\tUSERS\tIDENTITY\tLABELS")
+	for _, m := range mappings {
+		if len(m.Identities) == 0 {
+			fmt.Fprintf(w, "%s\t%d\t\t\n", m.Selector, m.Users)
+			continue
+		}
+		for i, id := range m.Identities {
+			selector, users := "", ""
+			if i == 0 {
+				selector, users = m.Selector, strconv.FormatInt(m.Users, 10)
+			}
+			lbls := "<unknown identity>"
+			if l := identities[id]; l != nil {
+				lbls = l.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", selector, users, id, lbls)
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestFilterSelectors(t *testing.T) {
+	cache := models.SelectorCache{
+		{Selector: "&LabelSelector{k8s:app=web}", Users: 2, Identities: []int64{1000, 1001}},
+		nil,
+		{Selector: "&LabelSelector{k8s:app=db}", Users: 1, Identities: []int64{1002}},
+		{Selector: "&LabelSelector{reserved:host}", Users: 0},
+	}
+	tests := []struct {
+		minUsers int64
+		want     []string
+	}{
+		{0, []string{"&LabelSelector{k8s:app=db}", "&LabelSelector{k8s:app=web}", "&LabelSelector{reserved:host}"}},
+		{1, []string{"&LabelSelector{k8s:app=db}", "&LabelSelector{k8s:app=web}"}},
+		{2, []string{"&LabelSelector{k8s:app=web}"}},
+		{3, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range filterSelectors(cache, tt.minUsers) {
+			got = append(got, m.Selector)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterSelectors(%d) = %v, want %v", tt.minUsers, got, tt.want)
+		}
+	}
+}
+
+func TestPrintSelectors(t *testing.T) {
+	mappings := []*models.SelectorIdentityMapping{
+		{Selector: "&LabelSelector{k8s:app=db}", Users: 1, Identities: []int64{1002}},
+		{Selector: "&LabelSelector{k8s:app=web}", Users: 2, Identities: []int64{1000, 1001}},
+	}
+	var buf bytes.Buffer
+	printSelectors(&buf, mappings)
+	want := "" +
+		"SELECTOR                      IDENTITIES   USERS\n" +
+		"&LabelSelector{k8s:app=db}    1            1\n" +
+		"&LabelSelector{k8s:app=web}   2            2\n"
+	if buf.String() != want {
+		t.Errorf("printSelectors() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestSelectorsResolve(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /policy/selectors", http.StatusOK, models.SelectorCache{
+		{Selector: "&LabelSelector{k8s:app=web}", Users: 2, Identities: []int64{1000, 1001}},
+		{Selector: "&LabelSelector{k8s:any=web}", Users: 1, Identities: []int64{1000}},
+		{Selector: "&LabelSelector{k8s:app=none}", Users: 1},
+	})
+	agent.respond("GET /identity/1000", http.StatusOK, &models.Identity{ID: 1000, Labels: models.Labels{"k8s:app=web"}})
+	agent.respond("GET /identity/1001", http.StatusNotFound, nil)
+
+	var out bytes.Buffer
+	if err := runSelectors(context.Background(), c, &out, []string{"-resolve"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"SELECTOR                       USERS   IDENTITY   LABELS\n" +
+		"&LabelSelector{k8s:any=web}    1       1000       k8s:app=web\n" +
+		"&LabelSelector{k8s:app=none}   1                  \n" +
+		"&LabelSelector{k8s:app=web}    2       1000       k8s:app=web\n" +
+		"                                       1001       <unknown identity>\n"
+	if out.String() != want {
+		t.Errorf("runSelectors() =\n%s\nwant\n%s", out.String(), want)
+	}
+	// Each identity is only looked up once.
+	if n := agent.callCount("GET /identity/1000"); n != 1 {
+		t.Errorf("identity 1000 was looked up %d times, want 1", n)
+	}
+}
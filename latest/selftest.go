@@ -0,0 +1,123 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/client/policy"
+	"github.com/cilium/cilium/api/v1/client/service"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "selftest",
+		usage:    "call every read-only API used by the client and print which ones fail, exit code 1 if any",
+		run:      runSelftest,
+		readOnly: true,
+	})
+}
+
+// selftestProbe is a read-only API call made by selftest. Probes do not
+// depend on each other, so all of them run even if some fail.
+type selftestProbe struct {
+	name string
+	call func(ctx context.Context, c *client.Client) error
+}
+
+var selftestProbes = []selftestProbe{
+	{"status", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+		return err
+	}},
+	{"config", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Daemon.GetConfig(daemon.NewGetConfigParamsWithContext(ctx))
+		return err
+	}},
+	{"endpoints", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+		return err
+	}},
+	{"identities", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Policy.GetIdentity(policy.NewGetIdentityParamsWithContext(ctx))
+		return err
+	}},
+	{"services", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Service.GetService(service.NewGetServiceParamsWithContext(ctx))
+		return err
+	}},
+	{"policy", func(ctx context.Context, c *client.Client) error {
+		_, err := c.Policy.GetPolicy(policy.NewGetPolicyParamsWithContext(ctx))
+		return err
+	}},
+}
+
+// selftestResult is the outcome of a probe.
+type selftestResult struct {
+	probe   string
+	latency time.Duration
+	err     error
+}
+
+func runSelftest(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	fs.Parse(args)
+
+	results := runSelftestProbes(ctx, c, selftestProbes)
+	printSelftestResults(out, results)
+	for _, r := range results {
+		if r.err != nil {
+			return errUnhealthy
+		}
+	}
+	return nil
+}
+
+// runSelftestProbes runs probes one after the other, each one bound by
+// -timeout-per-call if set, and records how long each call took.
+func runSelftestProbes(ctx context.Context, c *client.Client, probes []selftestProbe) []selftestResult {
+	results := make([]selftestResult, 0, len(probes))
+	for _, p := range probes {
+		callCtx, cancel := callContext(ctx)
+		start := time.Now()
+		err := p.call(callCtx, c)
+		cancel()
+		results = append(results, selftestResult{p.name, time.Since(start), err})
+	}
+	return results
+}
+
+func printSelftestResults(out io.Writer, results []selftestResult) {
+	w := tabwriter.NewWriter(out, 2, 0, 3, ' ', 0)
+	printHeader(w, "PROBE\tRESULT\tLATENCY\tERROR")
+	for _, r := range results {
+		result, msg := colorize(colorGreen, "pass"), ""
+		if r.err != nil {
+			result, msg = colorize(colorRed, "fail"), client.Hint(r.err).Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.probe, result, r.latency.Round(time.Microsecond), msg)
+	}
+	w.Flush()
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelftestFailingProbe(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, struct{}{})
+	agent.respond("GET /config", http.StatusOK, struct{}{})
+	agent.respond("GET /endpoint", http.StatusOK, []struct{}{})
+	agent.respond("GET /identity", http.StatusOK, []struct{}{})
+	agent.respond("GET /service", http.StatusInternalServerError, "service cache not ready")
+	agent.respond("GET /policy", http.StatusOK, struct{}{})
+
+	var buf bytes.Buffer
+	err := runSelftest(context.Background(), c, &buf, nil)
+	if !errors.Is(err, errUnhealthy) {
+		t.Errorf("runSelftest() = %v, want %v", err, errUnhealthy)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(selftestProbes)+1 {
+		t.Fatalf("got %d lines, want a header and %d probes:\n%s", len(lines), len(selftestProbes), buf.String())
+	}
+	for i, p := range selftestProbes {
+		fields := strings.Fields(lines[i+1])
+		want := "pass"
+		if p.name == "services" {
+			want = "fail"
+		}
+		if len(fields) < 3 || fields[0] != p.name || fields[1] != want {
+			t.Errorf("line %q, want probe %s to %s", lines[i+1], p.name, want)
+		}
+		if want == "fail" && !strings.Contains(lines[i+1], "500") {
+			t.Errorf("line %q does not report the status of the failure", lines[i+1])
+		}
+	}
+	// All probes run even though one failed.
+	for _, route := range []string{"GET /healthz", "GET /config", "GET /endpoint", "GET /identity", "GET /service", "GET /policy"} {
+		if n := agent.callCount(route); n != 1 {
+			t.Errorf("%s called %d times, want 1", route, n)
+		}
+	}
+}
+
+func TestSelftestTimeoutPerCall(t *testing.T) {
+	defer func(old time.Duration) { *timeoutPerCall = old }(*timeoutPerCall)
+	*timeoutPerCall = 50 * time.Millisecond
+	captureLogs(t)
+
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, struct{}{})
+	agent.handle("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	agent.respond("GET /endpoint", http.StatusOK, []struct{}{})
+
+	results := runSelftestProbes(context.Background(), c, selftestProbes[:3])
+	for _, r := range results {
+		slow := r.probe == "config"
+		if failed := r.err != nil; failed != slow {
+			t.Errorf("probe %s failed: %v, want %t", r.probe, r.err, slow)
+		}
+		if slow && !errors.Is(r.err, context.DeadlineExceeded) {
+			t.Errorf("probe %s failed with %v, want %v", r.probe, r.err, context.DeadlineExceeded)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "snapshot",
+		usage:    "save the endpoints to a file, or compare them against a saved file (-save FILE | -compare FILE)",
+		run:      runSnapshot,
+		readOnly: true,
+	})
+}
+
+func runSnapshot(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	save := fs.String("save", "", "file to save the endpoints to, compressed if it ends in .gz")
+	compare := fs.String("compare", "", "file saved with -save to compare the endpoints against")
+	fs.Parse(args)
+
+	if (*save == "") == (*compare == "") {
+		return errors.New("exactly one of -save and -compare must be given")
+	}
+	if *save != "" && *sockets != "" {
+		// The agents are queried concurrently and would all write to the
+		// same file.
+		return errors.New("-save cannot be used with -sockets, save the snapshot of each agent separately")
+	}
+
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	eps := resp.Payload
+	sortEndpoints(eps, endpointSortKeys["id"], false)
+
+	if *save != "" {
+		f, err := openOutput(*save)
+		if err != nil {
+			return err
+		}
+		if err := writeEndpointsJSON(f, eps, true, false); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Saved %d endpoints to %s\n", len(eps), *save)
+		return nil
+	}
+
+	saved, err := readSnapshot(*compare)
+	if err != nil {
+		return err
+	}
+	if n := printEndpointsDiff(out, saved, eps); n == 0 {
+		fmt.Fprintln(out, "No changes")
+	}
+	return nil
+}
+
+// readSnapshot reads the endpoints saved by snapshot -save.
+func readSnapshot(path string) ([]*models.Endpoint, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var eps []*models.Endpoint
+	if err := json.NewDecoder(r).Decode(&eps); err != nil {
+		return nil, fmt.Errorf("unable to parse snapshot %s: %w", path, err)
+	}
+	return eps, nil
+}
+
+// printEndpointsDiff prints the endpoints added and removed between the old
+// and new lists, and the changes of state, identity and labels of the others.
+// It returns the number of changes printed.
+func printEndpointsDiff(w io.Writer, old, new []*models.Endpoint) int {
+	changes := 0
+	oldByID := make(map[int64]*models.Endpoint, len(old))
+	identities := make(map[int64]*models.Identity, len(old))
+	for _, ep := range old {
+		oldByID[ep.ID] = ep
+		var id *models.Identity
+		if ep.Status != nil {
+			id = ep.Status.Identity
+		}
+		identities[ep.ID] = id
+	}
+	// Identity changes are detected as by identity-audit.
+	identityChanges := make(map[int64]identityChange)
+	for _, change := range auditIdentityChanges(identities, new) {
+		identityChanges[change.endpointID] = change
+	}
+	newByID := make(map[int64]*models.Endpoint, len(new))
+	for _, ep := range new {
+		newByID[ep.ID] = ep
+	}
+
+	for _, ep := range old {
+		if _, ok := newByID[ep.ID]; !ok {
+			fmt.Fprintf(w, "Endpoint %d: removed\n", ep.ID)
+			changes++
+		}
+	}
+	for _, ep := range new {
+		prev, ok := oldByID[ep.ID]
+		if !ok {
+			fmt.Fprintf(w, "Endpoint %d: added (%s)\n", ep.ID, endpointState(ep))
+			changes++
+			continue
+		}
+		if before, after := endpointState(prev), endpointState(ep); before != after {
+			fmt.Fprintf(w, "Endpoint %d: %s -> %s\n", ep.ID, before, after)
+			changes++
+		}
+		if change, ok := identityChanges[ep.ID]; ok {
+			fmt.Fprintf(w, "Endpoint %d: identity %d -> %d\n", ep.ID, identityID(change.old), identityID(change.new))
+			changes++
+		}
+		before, after := endpointLabels(prev), endpointLabels(ep)
+		added, removed := labelsDifference(after, before), labelsDifference(before, after)
+		if len(added) > 0 || len(removed) > 0 {
+			var diff []string
+			for _, l := range removed.GetPrintableModel() {
+				diff = append(diff, "-"+l)
+			}
+			for _, l := range added.GetPrintableModel() {
+				diff = append(diff, "+"+l)
+			}
+			fmt.Fprintf(w, "Endpoint %d: labels %s\n", ep.ID, strings.Join(diff, " "))
+			changes++
+		}
+	}
+	return changes
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestPrintEndpointsDiff(t *testing.T) {
+	base := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+		testEndpoint(2, models.EndpointStateReady, 1001, "k8s:app=db"),
+	}
+	tests := []struct {
+		name    string
+		new     []*models.Endpoint
+		want    string
+		changes int
+	}{
+		{
+			name: "unchanged",
+			new:  base,
+		},
+		{
+			name: "added and removed",
+			new: []*models.Endpoint{
+				base[0],
+				testEndpoint(3, models.EndpointStateWaitingForIdentity, 0),
+			},
+			want:    "Endpoint 2: removed\nEndpoint 3: added (waiting-for-identity)\n",
+			changes: 2,
+		},
+		{
+			name: "state",
+			new: []*models.Endpoint{
+				testEndpoint(1, models.EndpointStateRegenerating, 1000, "k8s:app=web"),
+				base[1],
+			},
+			want:    "Endpoint 1: ready -> regenerating\n",
+			changes: 1,
+		},
+		{
+			name: "identity and labels",
+			new: []*models.Endpoint{
+				base[0],
+				testEndpoint(2, models.EndpointStateReady, 1002, "k8s:app=cache"),
+			},
+			want:    "Endpoint 2: identity 1001 -> 1002\nEndpoint 2: labels -k8s:app=db +k8s:app=cache\n",
+			changes: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			changes := printEndpointsDiff(&buf, base, tt.new)
+			if changes != tt.changes {
+				t.Errorf("printEndpointsDiff() = %d changes, want %d", changes, tt.changes)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printEndpointsDiff() printed:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSnapshot(t *testing.T) {
+	for _, name := range []string{"snapshot.json", "snapshot.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			eps := []*models.Endpoint{
+				testEndpoint(1, models.EndpointStateReady, 1000, "k8s:app=web"),
+				testEndpoint(2, models.EndpointStateNotReady, 0),
+			}
+			path := filepath.Join(t.TempDir(), name)
+			f, err := openOutput(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeEndpointsJSON(f, eps, true, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readSnapshot(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, eps) {
+				t.Errorf("readSnapshot() = %v, want %v", got, eps)
+			}
+		})
+	}
+}
+
+func TestSnapshotSaveWithSockets(t *testing.T) {
+	defer func(old string) { *sockets = old }(*sockets)
+	*sockets = "/var/run/a.sock,/var/run/b.sock"
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	// The command must fail before making any API call, so no client is
+	// needed.
+	err := runSnapshot(context.Background(), nil, &bytes.Buffer{}, []string{"-save", path})
+	if err == nil {
+		t.Fatal("runSnapshot() succeeded with -save and -sockets")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("snapshot file written: %v", err)
+	}
+}
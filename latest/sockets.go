@@ -0,0 +1,71 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// runOnSockets runs cmd concurrently against the agents listening on each of
+// the sockets, and writes the output of each agent under a header naming its
+// socket. A failure on one socket does not stop the others.
+func runOnSockets(cmd *command, args []string, sockets []string, out io.Writer) error {
+	type result struct {
+		out bytes.Buffer
+		err error
+	}
+	results := make([]result, len(sockets))
+
+	var wg sync.WaitGroup
+	for i := range sockets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var w io.Writer = &results[i].out
+			if p, ok := out.(*prefixWriter); ok {
+				// Prefix the output of each agent on its own, so
+				// that JSON output can still bypass the prefix.
+				w = &prefixWriter{WriteCloser: nopCloser{w}, prefix: p.prefix}
+			}
+			results[i].err = runOnSocket(cmd, args, sockets[i], w)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "==> %s <==\n", sockets[i])
+		if r.err != nil {
+			fmt.Fprintf(out, "Command %s failed: %s\n", cmd.name, client.Hint(r.err))
+			failed++
+			continue
+		}
+		results[i].out.WriteTo(rawOutput(out))
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d agents", failed, len(sockets))
+	}
+	return nil
+}
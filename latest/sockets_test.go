@@ -0,0 +1,84 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+// fakeSelectorsAgent returns the host of a fake agent with a single selector
+// in its cache.
+func fakeSelectorsAgent(t *testing.T, selector string) string {
+	agent, _ := newFakeAgent(t)
+	agent.respond("GET /policy/selectors", http.StatusOK, models.SelectorCache{
+		{Selector: selector, Users: 1, Identities: []int64{1000}},
+	})
+	return agent.host
+}
+
+func TestRunOnSockets(t *testing.T) {
+	captureLogs(t)
+	a := fakeSelectorsAgent(t, "&LabelSelector{k8s:app=a}")
+	b := fakeSelectorsAgent(t, "&LabelSelector{k8s:app=b}")
+	broken, _ := newFakeAgent(t)
+	hosts := []string{a, broken.host, b}
+
+	var out bytes.Buffer
+	err := runOnSockets(commands["selectors"], []string{"-min-users", "1"}, hosts, &out)
+	if want := "command failed on 1 of 3 agents"; err == nil || err.Error() != want {
+		t.Errorf("runOnSockets() error = %v, want %s", err, want)
+	}
+
+	sections := strings.Split(out.String(), "\n\n")
+	if len(sections) != 3 {
+		t.Fatalf("runOnSockets() wrote %d sections, want 3:\n%s", len(sections), out.String())
+	}
+	// The output of each agent is kept in the order of the sockets.
+	// Splitting the sections drops their trailing newline.
+	for i, want := range []string{
+		"==> " + a + " <==\nSELECTOR                    IDENTITIES   USERS\n&LabelSelector{k8s:app=a}   1            1",
+		"==> " + broken.host + " <==\nCommand selectors failed: ",
+		"==> " + b + " <==\nSELECTOR                    IDENTITIES   USERS\n&LabelSelector{k8s:app=b}   1            1",
+	} {
+		if !strings.HasPrefix(sections[i], want) {
+			t.Errorf("section %d =\n%s\nwant\n%s", i, sections[i], want)
+		}
+	}
+}
+
+func TestRunOnSocketsPrefix(t *testing.T) {
+	a := fakeSelectorsAgent(t, "&LabelSelector{k8s:app=a}")
+
+	var buf bytes.Buffer
+	out := newPrefixWriter(nopCloser{&buf}, "prod")
+	if err := runOnSockets(commands["selectors"], nil, []string{a}, out); err != nil {
+		t.Fatal(err)
+	}
+	// Each line is prefixed exactly once.
+	want := "" +
+		"[prod] ==> " + a + " <==\n" +
+		"[prod] SELECTOR                    IDENTITIES   USERS\n" +
+		"[prod] &LabelSelector{k8s:app=a}   1            1\n"
+	if buf.String() != want {
+		t.Errorf("runOnSockets() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
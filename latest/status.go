@@ -0,0 +1,231 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+
+	"github.com/go-openapi/runtime"
+)
+
+// errUnhealthy is returned by commands reporting an unhealthy agent. The
+// client exits with code 1 without logging it, the command has already
+// printed the details.
+var errUnhealthy = errors.New("agent is unhealthy")
+
+func init() {
+	register(&command{
+		name:     "status",
+		usage:    "print the health of the agent and of the cluster mesh, exits with code 1 if it is degraded (-brief)",
+		run:      runStatus,
+		readOnly: true,
+	})
+}
+
+// statusSummary is the overall health of the agent.
+type statusSummary struct {
+	cilium             string
+	kvstore            string
+	endpoints          int
+	readyEndpoints     int
+	failingControllers int
+	clusters           int
+	readyClusters      int
+}
+
+func newStatusSummary(sr *models.StatusResponse, eps []*models.Endpoint) statusSummary {
+	s := statusSummary{cilium: "unknown", kvstore: "unknown", endpoints: len(eps)}
+	if sr.Cilium != nil {
+		s.cilium = sr.Cilium.State
+	}
+	if sr.Kvstore != nil {
+		s.kvstore = sr.Kvstore.State
+	}
+	for _, ep := range eps {
+		if endpointState(ep) == string(models.EndpointStateReady) {
+			s.readyEndpoints++
+		}
+	}
+	if sr.ClusterMesh != nil {
+		for _, cluster := range sr.ClusterMesh.Clusters {
+			s.clusters++
+			if cluster.Ready {
+				s.readyClusters++
+			}
+		}
+	}
+	s.failingControllers = len(failingControllers(sr.Controllers))
+	return s
+}
+
+// healthy returns true if the agent and the kvstore are ok, and all endpoints,
+// controllers and remote clusters are working.
+func (s statusSummary) healthy() bool {
+	return s.cilium == models.StatusStateOk &&
+		(s.kvstore == models.StatusStateOk || s.kvstore == models.StatusStateDisabled) &&
+		s.readyEndpoints == s.endpoints &&
+		s.failingControllers == 0 &&
+		s.readyClusters == s.clusters
+}
+
+// brief returns the summary on a single line, e.g.
+// "OK endpoints=42/42 ready kvstore=ok controllers=0 failing".
+func (s statusSummary) brief() string {
+	health := "OK"
+	if !s.healthy() {
+		health = "DEGRADED"
+	}
+	line := fmt.Sprintf("%s endpoints=%d/%d ready kvstore=%s controllers=%d failing",
+		health, s.readyEndpoints, s.endpoints, strings.ToLower(s.kvstore), s.failingControllers)
+	if s.clusters > 0 {
+		line += fmt.Sprintf(" clusters=%d/%d ready", s.readyClusters, s.clusters)
+	}
+	return line
+}
+
+func runStatus(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	brief := fs.Bool("brief", false, "print a single line summary")
+	agentTime := fs.Bool("agent-time", false, "print the skew between the clocks of the agent and of the client")
+	maxSkew := fs.Duration("max-skew", 5*time.Second, "warn if the clock skew exceeds this duration (with -agent-time)")
+	fs.Parse(args)
+
+	var date string
+	if *agentTime {
+		// The agent time is taken from the Date header of the response.
+		transport := c.Transport
+		c.SetTransport(headerTransport{transport, "Date", &date})
+		defer c.SetTransport(transport)
+	}
+	sr, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	warnIncompatibleAgent(sr.Payload)
+	var skew time.Duration
+	if *agentTime {
+		if skew, err = clockSkew(date, time.Now()); err != nil {
+			return err
+		}
+		if skew > *maxSkew || skew < -*maxSkew {
+			log.WithField("skew", skew).Warningf("Clock skew between agent and client exceeds %s", *maxSkew)
+		}
+	}
+	eps, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return err
+	}
+	s := newStatusSummary(sr.Payload, eps.Payload)
+
+	if *brief {
+		color := colorGreen
+		if !s.healthy() {
+			color = colorYellow
+		}
+		line := s.brief()
+		if *agentTime {
+			line += fmt.Sprintf(" skew=%s", skew)
+		}
+		fmt.Fprintln(out, colorize(color, line))
+	} else {
+		if err := printStatusComponents(out, sr.Payload); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Endpoints: %d/%d ready\n", s.readyEndpoints, s.endpoints)
+		fmt.Fprintf(out, "Controllers: %d failing\n", s.failingControllers)
+		if s.clusters > 0 {
+			fmt.Fprintf(out, "Cluster mesh: %d/%d remote clusters ready\n", s.readyClusters, s.clusters)
+			printRemoteClusters(out, sr.Payload.ClusterMesh.Clusters)
+		}
+		if *agentTime {
+			fmt.Fprintf(out, "Clock skew: %s\n", skew)
+		}
+	}
+	if !s.healthy() {
+		return errUnhealthy
+	}
+	return nil
+}
+
+// printRemoteClusters prints the connection status of the remote clusters of
+// the cluster mesh, sorted by name. Clusters which are not ready are flagged.
+func printRemoteClusters(w io.Writer, clusters []*models.RemoteCluster) {
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	tw := tabwriter.NewWriter(w, 2, 0, 3, ' ', 0)
+	printHeader(tw, "  NAME\tREADY\tNODES\tIDENTITIES\tSERVICES\tFAILURES\tLAST FAILURE\tSTATUS")
+	for _, cluster := range clusters {
+		ready := colorize(colorGreen, "yes")
+		if !cluster.Ready {
+			ready = colorize(colorRed, "no")
+		}
+		lastFailure := "-"
+		if cluster.NumFailures > 0 {
+			lastFailure = cluster.LastFailure.String()
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", cluster.Name, ready,
+			cluster.NumNodes, cluster.NumIdentities, cluster.NumSharedServices,
+			cluster.NumFailures, lastFailure, cluster.Status)
+	}
+	tw.Flush()
+}
+
+// headerTransport records the value of a header of the responses to the API
+// calls made through it.
+type headerTransport struct {
+	runtime.ClientTransport
+	name  string
+	value *string
+}
+
+func (t headerTransport) Submit(op *runtime.ClientOperation) (interface{}, error) {
+	reader := op.Reader
+	op.Reader = runtime.ClientResponseReaderFunc(func(resp runtime.ClientResponse, consumer runtime.Consumer) (interface{}, error) {
+		*t.value = resp.GetHeader(t.name)
+		return reader.ReadResponse(resp, consumer)
+	})
+	return t.ClientTransport.Submit(op)
+}
+
+// clockSkew returns how far ahead of now the agent clock is, given the Date
+// header of a response of the agent received at now. The header only has a
+// precision of one second.
+func clockSkew(date string, now time.Time) (time.Duration, error) {
+	if date == "" {
+		return 0, errors.New("agent did not send its time")
+	}
+	agentTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid agent time %q: %w", date, err)
+	}
+	return agentTime.Sub(now.Truncate(time.Second)), nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/go-openapi/strfmt"
+)
+
+func TestStatusSummaryBrief(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       statusSummary
+		healthy bool
+		want    string
+	}{
+		{"healthy", statusSummary{cilium: "Ok", kvstore: "Ok", endpoints: 2, readyEndpoints: 2},
+			true, "OK endpoints=2/2 ready kvstore=ok controllers=0 failing"},
+		{"kvstore disabled", statusSummary{cilium: "Ok", kvstore: "Disabled"},
+			true, "OK endpoints=0/0 ready kvstore=disabled controllers=0 failing"},
+		{"endpoint not ready", statusSummary{cilium: "Ok", kvstore: "Ok", endpoints: 2, readyEndpoints: 1},
+			false, "DEGRADED endpoints=1/2 ready kvstore=ok controllers=0 failing"},
+		{"failing controller", statusSummary{cilium: "Ok", kvstore: "Ok", failingControllers: 3},
+			false, "DEGRADED endpoints=0/0 ready kvstore=ok controllers=3 failing"},
+		{"agent warning", statusSummary{cilium: "Warning", kvstore: "Ok"},
+			false, "DEGRADED endpoints=0/0 ready kvstore=ok controllers=0 failing"},
+		{"clusters", statusSummary{cilium: "Ok", kvstore: "Ok", clusters: 2, readyClusters: 1},
+			false, "DEGRADED endpoints=0/0 ready kvstore=ok controllers=0 failing clusters=1/2 ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.healthy(); got != tt.healthy {
+				t.Errorf("healthy() = %v, want %v", got, tt.healthy)
+			}
+			if got := tt.s.brief(); got != tt.want {
+				t.Errorf("brief() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStatusSummary(t *testing.T) {
+	sr := &models.StatusResponse{
+		Cilium: &models.Status{State: models.StatusStateOk},
+		ClusterMesh: &models.ClusterMeshStatus{Clusters: []*models.RemoteCluster{
+			{Name: "a", Ready: true},
+			{Name: "b"},
+		}},
+	}
+	eps := []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 100),
+		testEndpoint(2, models.EndpointStateWaitingForIdentity, 0),
+	}
+	got := newStatusSummary(sr, eps)
+	want := statusSummary{
+		cilium:         models.StatusStateOk,
+		kvstore:        "unknown",
+		endpoints:      2,
+		readyEndpoints: 1,
+		clusters:       2,
+		readyClusters:  1,
+	}
+	if got != want {
+		t.Errorf("newStatusSummary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatusBrief(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Cilium:  &models.Status{State: models.StatusStateOk},
+		Kvstore: &models.Status{State: models.StatusStateOk},
+	})
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 100),
+		testEndpoint(2, models.EndpointStateNotReady, 101),
+	})
+
+	var out bytes.Buffer
+	err := runStatus(context.Background(), c, &out, []string{"-brief"})
+	if !errors.Is(err, errUnhealthy) {
+		t.Errorf("runStatus() error = %v, want %v", err, errUnhealthy)
+	}
+	want := "DEGRADED endpoints=1/2 ready kvstore=ok controllers=0 failing\n"
+	if out.String() != want {
+		t.Errorf("runStatus() output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	tests := []struct {
+		name    string
+		date    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"in sync", "Tue, 01 Jun 2021 12:00:00 GMT", 0, false},
+		{"agent ahead", "Tue, 01 Jun 2021 12:00:07 GMT", 7 * time.Second, false},
+		{"agent behind", "Tue, 01 Jun 2021 11:59:00 GMT", -time.Minute, false},
+		{"missing", "", 0, true},
+		{"invalid", "yesterday", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clockSkew(tt.date, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clockSkew() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("clockSkew() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusAgentTime(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Cilium:  &models.Status{State: models.StatusStateOk},
+		Kvstore: &models.Status{State: models.StatusStateOk},
+	})
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{})
+
+	// The fake agent shares the clock of the test, so only the presence
+	// of the skew is checked.
+	var out bytes.Buffer
+	if err := runStatus(context.Background(), c, &out, []string{"-brief", "-agent-time"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), " skew=") {
+		t.Errorf("runStatus() output = %q, want a skew", out.String())
+	}
+}
+
+func TestPrintRemoteClusters(t *testing.T) {
+	lastFailure := strfmt.DateTime(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	clusters := []*models.RemoteCluster{
+		{Name: "east", NumFailures: 2, LastFailure: lastFailure, Status: "Waiting for initial connection"},
+		{Name: "default", Ready: true, NumNodes: 3, NumIdentities: 12, NumSharedServices: 4, Status: "ready"},
+	}
+	var buf bytes.Buffer
+	printRemoteClusters(&buf, clusters)
+	want := "" +
+		"  NAME      READY   NODES   IDENTITIES   SERVICES   FAILURES   LAST FAILURE               STATUS\n" +
+		"  default   yes     3       12           4          0          -                          ready\n" +
+		"  east      no      0       0            0          2          2021-06-01T12:00:00.000Z   Waiting for initial connection\n"
+	if buf.String() != want {
+		t.Errorf("printRemoteClusters() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestStatusRemoteClusters(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Cilium:  &models.Status{State: models.StatusStateOk},
+		Kvstore: &models.Status{State: models.StatusStateOk},
+		ClusterMesh: &models.ClusterMeshStatus{Clusters: []*models.RemoteCluster{
+			{Name: "default", Ready: true},
+			{Name: "east"},
+		}},
+	})
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{})
+
+	var out bytes.Buffer
+	if err := runStatus(context.Background(), c, &out, nil); !errors.Is(err, errUnhealthy) {
+		t.Errorf("runStatus() error = %v, want %v", err, errUnhealthy)
+	}
+	if !strings.Contains(out.String(), "Cluster mesh: 1/2 remote clusters ready\n") {
+		t.Errorf("runStatus() output does not show the cluster mesh:\n%s", out.String())
+	}
+}
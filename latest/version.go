@@ -0,0 +1,153 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+// version is the version of the client. It is set at build time with
+//
+//	go build -ldflags "-X main.version=v1.0.0"
+var version = "dev"
+
+// apiVersion is the Cilium minor release the vendored API models were taken
+// from. Agents of other releases may not implement the same models.
+var apiVersion = [2]int{1, 10}
+
+func init() {
+	register(&command{
+		name:     "version",
+		usage:    "print the client and agent versions",
+		run:      runVersion,
+		readOnly: true,
+	})
+}
+
+func runVersion(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Parse(args)
+
+	resp, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		printVersion(out, "", err)
+		return nil
+	}
+	agentVersion, err := agentVersionFromStatus(resp.Payload)
+	printVersion(out, agentVersion, err)
+	warnIncompatibleAgent(resp.Payload)
+	return nil
+}
+
+// printVersion prints the client version and the agent version, or why the
+// latter is not available.
+func printVersion(w io.Writer, agentVersion string, agentErr error) {
+	fmt.Fprintf(w, "Client: %s\n", version)
+	if agentErr != nil {
+		fmt.Fprintf(w, "Agent:  unavailable (%s)\n", agentErr)
+		return
+	}
+	fmt.Fprintf(w, "Agent:  %s\n", agentVersion)
+}
+
+// parseAgentVersion returns the major and minor release of the agent from its
+// version string, e.g. "1.10.0 4a831f4 2021-05-18T16:38:19+02:00 go version
+// go1.16.4 linux/amd64".
+func parseAgentVersion(agentVersion string) (major, minor int, err error) {
+	fields := strings.Fields(agentVersion)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("empty agent version")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(fields[0], "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid agent version %q", fields[0])
+	}
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid agent version %q: %w", fields[0], err)
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("invalid agent version %q: %w", fields[0], err)
+	}
+	return major, minor, nil
+}
+
+// errUnknownAgentVersion is returned by checkCompatibility when the status
+// does not tell which release the agent runs.
+var errUnknownAgentVersion = errors.New("unknown agent version")
+
+// agentVersionFromStatus returns the version of the agent which reported
+// status, as found at the start of the message of the Cilium component, e.g.
+// "1.10.0 (v1.10.0-4a831f4)". The message of a failing agent is not a
+// version, and errUnknownAgentVersion is returned then.
+func agentVersionFromStatus(status *models.StatusResponse) (string, error) {
+	if status == nil || status.Cilium == nil {
+		return "", errUnknownAgentVersion
+	}
+	if _, _, err := parseAgentVersion(status.Cilium.Msg); err != nil {
+		return "", fmt.Errorf("%w: %s", errUnknownAgentVersion, err)
+	}
+	return status.Cilium.Msg, nil
+}
+
+// checkCompatibility returns an error if the agent which reported status runs
+// a different release than the one the API models were vendored from. In
+// that case fields the agent does not know about are left empty, and fields
+// the client does not know about are not shown. The agent reports its
+// version at the start of the message of the Cilium component, e.g.
+// "1.10.0 (v1.10.0-4a831f4)", unless it is failing.
+func checkCompatibility(status *models.StatusResponse) error {
+	if status == nil || status.Cilium == nil {
+		return errUnknownAgentVersion
+	}
+	major, minor, err := parseAgentVersion(status.Cilium.Msg)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUnknownAgentVersion, err)
+	}
+	switch {
+	case major < apiVersion[0] || major == apiVersion[0] && minor < apiVersion[1]:
+		return fmt.Errorf("agent version %d.%d is older than the API version %d.%d of the client, some fields may be empty",
+			major, minor, apiVersion[0], apiVersion[1])
+	case major > apiVersion[0] || minor > apiVersion[1]:
+		return fmt.Errorf("agent version %d.%d is newer than the API version %d.%d of the client, some fields may be missing",
+			major, minor, apiVersion[0], apiVersion[1])
+	}
+	return nil
+}
+
+// warnIncompatibleAgent logs a warning if the agent which reported status
+// runs a release the client was not built for. The client keeps operating in
+// that case. It is only called by the commands fetching the status anyway, so
+// that the check does not cost an API call of its own.
+func warnIncompatibleAgent(status *models.StatusResponse) {
+	err := checkCompatibility(status)
+	switch {
+	case errors.Is(err, errUnknownAgentVersion):
+		log.WithError(err).Debug("Skipping agent compatibility check")
+	case err != nil:
+		log.WithError(err).Warning("Agent version does not match the client API version")
+	}
+}
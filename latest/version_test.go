@@ -0,0 +1,105 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	statusWithMsg := func(msg string) *models.StatusResponse {
+		return &models.StatusResponse{Cilium: &models.Status{State: models.StatusStateOk, Msg: msg}}
+	}
+	tests := []struct {
+		name    string
+		status  *models.StatusResponse
+		wantErr bool
+		unknown bool
+	}{
+		{"matching", statusWithMsg("1.10.4 (v1.10.4-2a46fd6)"), false, false},
+		{"matching with v prefix", statusWithMsg("v1.10.0"), false, false},
+		{"older", statusWithMsg("1.9.8 (v1.9.8-4a831f4)"), true, false},
+		{"newer", statusWithMsg("1.11.0 (v1.11.0-27e0848)"), true, false},
+		{"newer major", statusWithMsg("2.0.0"), true, false},
+		{"failing agent", statusWithMsg("Kvstore service is not ready"), true, true},
+		{"no cilium status", &models.StatusResponse{}, true, true},
+		{"no status", nil, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCompatibility(tt.status)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkCompatibility() = %v, want error %t", err, tt.wantErr)
+			}
+			if errors.Is(err, errUnknownAgentVersion) != tt.unknown {
+				t.Errorf("checkCompatibility() = %v, want unknown version %t", err, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"running", "1.10.0 (v1.10.0-4a831f4)", "Agent:  1.10.0 (v1.10.0-4a831f4)\n"},
+		{"failing", "Kvstore service is not ready", "Agent:  unavailable (unknown agent version: invalid agent version \"Kvstore\")\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			agent, c := newFakeAgent(t)
+			agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+				Cilium: &models.Status{State: models.StatusStateOk, Msg: tt.msg},
+			})
+
+			var out bytes.Buffer
+			if err := runVersion(context.Background(), c, &out, nil); err != nil {
+				t.Fatal(err)
+			}
+			if want := "Client: dev\n" + tt.want; out.String() != want {
+				t.Errorf("runVersion() = %q, want %q", out.String(), want)
+			}
+			if n := agent.callCount("GET /debuginfo"); n != 0 {
+				t.Errorf("runVersion() fetched the debug info %d times", n)
+			}
+		})
+	}
+}
+
+func TestVersionAgentUnavailable(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusInternalServerError, "agent is shutting down")
+
+	// The client version is printed even if the agent cannot be reached.
+	var out bytes.Buffer
+	if err := runVersion(context.Background(), c, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "Client: dev\nAgent:  unavailable (") {
+		t.Errorf("runVersion() = %q, want the agent to be unavailable", got)
+	}
+}
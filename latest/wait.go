@@ -0,0 +1,150 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+const (
+	// waitMinInterval and waitMaxInterval bound the exponential backoff
+	// between two polls of the endpoint states.
+	waitMinInterval = 100 * time.Millisecond
+	waitMaxInterval = 2 * time.Second
+)
+
+func init() {
+	register(&command{
+		name:     "wait",
+		usage:    "wait until an endpoint, or all endpoints, reach a state (-id N, -state ready)",
+		run:      runWait,
+		readOnly: true,
+	})
+}
+
+func runWait(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the endpoint to wait for, all endpoints if empty")
+	state := fs.String("state", string(models.EndpointStateReady), "comma separated list of states to wait for")
+	fs.Parse(args)
+
+	targets, err := parseEndpointStates(*state)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return errors.New("missing state, use -state")
+	}
+
+	pending, err := waitForEndpoints(ctx, c, out, *id, targets)
+	if err != nil && ctx.Err() != nil {
+		printPendingEndpoints(out, pending, *state)
+	}
+	return err
+}
+
+// waitForEndpoints polls the endpoint with the given ID, or all endpoints if
+// id is empty, until they are all in one of the target states. State changes
+// are printed to w. Transient errors are retried. If ctx is done first, the
+// IDs of the endpoints not in a target state yet are returned along with the
+// error.
+func waitForEndpoints(ctx context.Context, c *client.Client, w io.Writer, id string, targets map[models.EndpointState]struct{}) ([]string, error) {
+	states := make(map[int64]string)
+	var pending []string
+	interval := waitMinInterval
+	for {
+		eps, err := waitPoll(ctx, c, id)
+		switch {
+		case err == nil:
+			pending = printStateTransitions(w, states, eps, targets)
+			if len(pending) == 0 {
+				return nil, nil
+			}
+		case !isRetryable(err) || ctx.Err() != nil:
+			return pending, err
+		default:
+			log.WithError(client.Hint(err)).Debug("Unable to list endpoints, retrying")
+		}
+
+		select {
+		case <-ctx.Done():
+			return pending, ctx.Err()
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+// printPendingEndpoints prints the IDs of the endpoints which did not reach
+// the target state in time.
+func printPendingEndpoints(w io.Writer, pending []string, state string) {
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d endpoints not in state %s: %s\n", len(pending), state, strings.Join(pending, ", "))
+}
+
+// waitPoll returns the endpoint with the given ID, or all endpoints if id is
+// empty.
+func waitPoll(ctx context.Context, c *client.Client, id string) ([]*models.Endpoint, error) {
+	if id != "" {
+		ep, err := getEndpoint(ctx, c, id)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Endpoint{ep}, nil
+	}
+	resp, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// printStateTransitions prints the endpoints whose state changed since the
+// last poll and records their new state in states. It returns the IDs of the
+// endpoints not yet in one of the target states.
+func printStateTransitions(w io.Writer, states map[int64]string, eps []*models.Endpoint, targets map[models.EndpointState]struct{}) []string {
+	sortEndpoints(eps, endpointSortKeys["id"], false)
+	var pending []string
+	for _, ep := range eps {
+		state := endpointState(ep)
+		if prev, ok := states[ep.ID]; !ok {
+			fmt.Fprintf(w, "Endpoint %d: %s\n", ep.ID, state)
+		} else if prev != state {
+			fmt.Fprintf(w, "Endpoint %d: %s -> %s\n", ep.ID, prev, state)
+		}
+		states[ep.ID] = state
+		if _, ok := targets[models.EndpointState(state)]; !ok {
+			pending = append(pending, strconv.FormatInt(ep.ID, 10))
+		}
+	}
+	return pending
+}
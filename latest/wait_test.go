@@ -0,0 +1,105 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestPrintStateTransitions(t *testing.T) {
+	targets := map[models.EndpointState]struct{}{models.EndpointStateReady: {}}
+	states := make(map[int64]string)
+	polls := []struct {
+		eps     []*models.Endpoint
+		want    string
+		pending []string
+	}{
+		{[]*models.Endpoint{
+			testEndpoint(2, models.EndpointStateWaitingForIdentity, 0),
+			testEndpoint(1, models.EndpointStateReady, 100),
+		}, "Endpoint 1: ready\nEndpoint 2: waiting-for-identity\n", []string{"2"}},
+		// Unchanged states are not printed again.
+		{[]*models.Endpoint{
+			testEndpoint(1, models.EndpointStateReady, 100),
+			testEndpoint(2, models.EndpointStateRegenerating, 101),
+		}, "Endpoint 2: waiting-for-identity -> regenerating\n", []string{"2"}},
+		{[]*models.Endpoint{
+			testEndpoint(1, models.EndpointStateReady, 100),
+			testEndpoint(2, models.EndpointStateReady, 101),
+		}, "Endpoint 2: regenerating -> ready\n", nil},
+	}
+	for i, p := range polls {
+		var buf bytes.Buffer
+		pending := printStateTransitions(&buf, states, p.eps, targets)
+		if buf.String() != p.want {
+			t.Errorf("poll %d: printed %q, want %q", i, buf.String(), p.want)
+		}
+		if !reflect.DeepEqual(pending, p.pending) {
+			t.Errorf("poll %d: pending = %v, want %v", i, pending, p.pending)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.handle("GET /endpoint/1", func(w http.ResponseWriter, r *http.Request) {
+		state := models.EndpointStateWaitingForIdentity
+		if agent.callCount("GET /endpoint/1") > 1 {
+			state = models.EndpointStateReady
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testEndpoint(1, state, 100))
+	})
+
+	var out bytes.Buffer
+	if err := runWait(context.Background(), c, &out, []string{"-id", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Endpoint 1: waiting-for-identity\nEndpoint 1: waiting-for-identity -> ready\n"
+	if out.String() != want {
+		t.Errorf("runWait() output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateReady, 100),
+		testEndpoint(2, models.EndpointStateNotReady, 101),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*waitMinInterval/2)
+	defer cancel()
+	var out bytes.Buffer
+	if err := runWait(ctx, c, &out, nil); err != context.DeadlineExceeded {
+		t.Errorf("runWait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	want := "Endpoint 1: ready\nEndpoint 2: not-ready\n1 endpoints not in state ready: 2\n"
+	if out.String() != want {
+		t.Errorf("runWait() output = %q, want %q", out.String(), want)
+	}
+	if n := agent.callCount("GET /endpoint"); n < 2 {
+		t.Errorf("agent was polled %d times, want at least 2", n)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/client/daemon"
+	"github.com/cilium/cilium/api/v1/client/endpoint"
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/client"
+)
+
+func init() {
+	register(&command{
+		name:     "watch",
+		usage:    "poll the agent until all predicates hold or -timeout expires (-until endpoints.ready==all,...)",
+		run:      runWatch,
+		readOnly: true,
+	})
+}
+
+// watchPredicates are the conditions which can be waited for with -until.
+var watchPredicates = map[string]func(s statusSummary) bool{
+	"endpoints.ready==all": func(s statusSummary) bool {
+		return s.readyEndpoints == s.endpoints
+	},
+	"controllers.failing==0": func(s statusSummary) bool {
+		return s.failingControllers == 0
+	},
+	// A disabled kvstore is fine, as for statusSummary.healthy.
+	"kvstore==ok": func(s statusSummary) bool {
+		return s.kvstore == models.StatusStateOk || s.kvstore == models.StatusStateDisabled
+	},
+}
+
+func watchPredicateNames() []string {
+	names := make([]string, 0, len(watchPredicates))
+	for name := range watchPredicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseWatchPredicates parses a comma separated list of predicate names.
+func parseWatchPredicates(str string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(str, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := watchPredicates[name]; !ok {
+			return nil, fmt.Errorf("unknown predicate %q, must be one of: %s", name, strings.Join(watchPredicateNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func runWatch(ctx context.Context, c *client.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	until := fs.String("until", "", "comma separated list of predicates which must all hold, one of: "+strings.Join(watchPredicateNames(), ", "))
+	fs.Parse(args)
+
+	names, err := parseWatchPredicates(*until)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("missing predicate, use -until")
+	}
+
+	var failing []string
+	interval := waitMinInterval
+	for {
+		s, err := pollStatusSummary(ctx, c)
+		if err == nil {
+			failing = failing[:0]
+			for _, name := range names {
+				if !watchPredicates[name](s) {
+					failing = append(failing, name)
+				}
+			}
+			if len(failing) == 0 {
+				fmt.Fprintf(out, "%s: true\n", strings.Join(names, ","))
+				return nil
+			}
+		} else if !isRetryable(err) {
+			return err
+		} else if ctx.Err() == nil {
+			// The agent may not be up yet, keep polling until the deadline.
+			log.WithError(client.Hint(err)).Debug("Unable to get agent status")
+		}
+
+		select {
+		case <-ctx.Done():
+			if len(failing) > 0 {
+				fmt.Fprintf(out, "%s: false\n", strings.Join(failing, ","))
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
+
+// pollStatusSummary fetches the status and the endpoints of the agent.
+func pollStatusSummary(ctx context.Context, c *client.Client) (statusSummary, error) {
+	sr, err := c.Daemon.GetHealthz(daemon.NewGetHealthzParamsWithContext(ctx))
+	if err != nil {
+		return statusSummary{}, err
+	}
+	eps, err := c.Endpoint.GetEndpoint(endpoint.NewGetEndpointParamsWithContext(ctx))
+	if err != nil {
+		return statusSummary{}, err
+	}
+	return newStatusSummary(sr.Payload, eps.Payload), nil
+}
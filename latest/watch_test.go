@@ -0,0 +1,129 @@
+// Copyright 2021 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/models"
+)
+
+func TestParseWatchPredicates(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"kvstore==ok", []string{"kvstore==ok"}, false},
+		{" endpoints.ready==all , controllers.failing==0,", []string{"endpoints.ready==all", "controllers.failing==0"}, false},
+		{"kvstore==ok,endpoints==all", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseWatchPredicates(tt.str)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWatchPredicates(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWatchPredicates(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestWatchPredicates(t *testing.T) {
+	tests := []struct {
+		name string
+		s    statusSummary
+		want []string
+	}{
+		{"all hold", statusSummary{kvstore: models.StatusStateOk, endpoints: 2, readyEndpoints: 2},
+			[]string{"controllers.failing==0", "endpoints.ready==all", "kvstore==ok"}},
+		{"kvstore disabled", statusSummary{kvstore: models.StatusStateDisabled, endpoints: 2, readyEndpoints: 1},
+			[]string{"controllers.failing==0", "kvstore==ok"}},
+		{"kvstore warning", statusSummary{kvstore: models.StatusStateWarning, endpoints: 2, readyEndpoints: 2},
+			[]string{"controllers.failing==0", "endpoints.ready==all"}},
+		{"none hold", statusSummary{kvstore: models.StatusStateFailure, endpoints: 2, readyEndpoints: 1, failingControllers: 1},
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, name := range watchPredicateNames() {
+				if watchPredicates[name](tt.s) {
+					got = append(got, name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("predicates holding = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchUntil(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Kvstore: &models.Status{State: models.StatusStateOk},
+	})
+	// The endpoint becomes ready on the third poll.
+	agent.handle("GET /endpoint", func(w http.ResponseWriter, r *http.Request) {
+		state := models.EndpointStateRegenerating
+		if agent.callCount("GET /endpoint") >= 3 {
+			state = models.EndpointStateReady
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]*models.Endpoint{testEndpoint(1, state, 100)})
+	})
+
+	var out bytes.Buffer
+	if err := runWatch(context.Background(), c, &out, []string{"-until", "kvstore==ok,endpoints.ready==all"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "kvstore==ok,endpoints.ready==all: true\n"; out.String() != want {
+		t.Errorf("runWatch() = %q, want %q", out.String(), want)
+	}
+	if n := agent.callCount("GET /endpoint"); n != 3 {
+		t.Errorf("agent was polled %d times, want 3", n)
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	agent, c := newFakeAgent(t)
+	agent.respond("GET /healthz", http.StatusOK, &models.StatusResponse{
+		Kvstore: &models.Status{State: models.StatusStateOk},
+	})
+	agent.respond("GET /endpoint", http.StatusOK, []*models.Endpoint{
+		testEndpoint(1, models.EndpointStateNotReady, 100),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*waitMinInterval/2)
+	defer cancel()
+	var out bytes.Buffer
+	err := runWatch(ctx, c, &out, []string{"-until", "kvstore==ok,endpoints.ready==all"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("runWatch() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Only the predicates which do not hold are reported.
+	if want := "endpoints.ready==all: false\n"; out.String() != want {
+		t.Errorf("runWatch() = %q, want %q", out.String(), want)
+	}
+}